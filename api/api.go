@@ -3,6 +3,8 @@ package api
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -23,9 +25,23 @@ var UserAgent string
 // Debug turns on debugging of http requests
 var Debug bool
 
+// DryRun prints requests that would change anything instead of sending them
+var DryRun bool
+
+// AfterWrite is called after each request that may have changed something, such as to forget cached responses
+var AfterWrite func(req *http.Request)
+
+// ErrDryRun is returned for requests that were printed instead of sent because of DryRun
+var ErrDryRun = errors.New("dry run, not sent")
+
 type loggingTransport struct{}
 
 func (lt *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	isWrite := req.Method != http.MethodGet && req.Method != http.MethodHead
+	if DryRun && isWrite {
+		return nil, printDryRun(req)
+	}
+
 	if Debug {
 		if req.Body != nil {
 			body, _ := ioutil.ReadAll(req.Body)
@@ -37,6 +53,9 @@ func (lt *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	}
 
 	resp, err := http.DefaultTransport.RoundTrip(req)
+	if isWrite && AfterWrite != nil {
+		AfterWrite(req)
+	}
 
 	if Debug && req.Body != nil && err != nil {
 		body, err := ioutil.ReadAll(req.Body)
@@ -49,6 +68,26 @@ func (lt *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	return resp, err
 }
 
+// printDryRun prints the method, path and body of a request, returning ErrDryRun
+func printDryRun(req *http.Request) error {
+	fmt.Printf("%s %s\n", req.Method, req.URL.RequestURI())
+	if req.Body == nil {
+		return ErrDryRun
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	var indented bytes.Buffer
+	if json.Indent(&indented, body, "", "  ") == nil {
+		body = indented.Bytes()
+	}
+	if len(body) > 0 {
+		fmt.Println(string(body))
+	}
+	return ErrDryRun
+}
+
 // Initialize sets up api for use with a given user agent string
 func Initialize(userAgent string) {
 	UserAgent = userAgent
@@ -58,24 +97,56 @@ func Initialize(userAgent string) {
 	}
 }
 
-// GetClient returns a client for the given server
-func GetClient(server string) (*ldapi.APIClient, error) {
+func basePath(server string) (string, error) {
 	if server == "" {
 		server = defaultServerURL
 	}
 	url, err := url.Parse(server)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parser server: %s", err)
+		return "", fmt.Errorf("unable to parser server: %s", err)
 	}
 	url.Path = "/api/v2"
 	url.RawPath = ""
+	return url.String(), nil
+}
+
+// GetClient returns a client for the given server
+func GetClient(server string) (*ldapi.APIClient, error) {
+	base, err := basePath(server)
+	if err != nil {
+		return nil, err
+	}
 	return ldapi.NewAPIClient(&ldapi.Configuration{
-		BasePath:   url.String(),
+		BasePath:   base,
 		HTTPClient: HTTPClient,
 		UserAgent:  UserAgent,
 	}), nil
 }
 
+// GetJSON fetches a resource from the api into result.  It is used for fields that the api client does not support.
+func GetJSON(server string, token string, resourcePath string, result interface{}) error {
+	base, err := basePath(server)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodGet, base+resourcePath, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", token)
+	req.Header.Set("User-Agent", UserAgent)
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint:errcheck // ok to ignore failure to close body
+	if resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return NewError(resp.StatusCode, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
 // GetAuthCtx returns a context that can be used to access the api
 func GetAuthCtx(token string) context.Context {
 	return context.WithValue(context.Background(), ldapi.ContextAPIKey, ldapi.APIKey{
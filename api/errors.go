@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrConflict matches errors from changes that fail because the resource has been changed since it was fetched
+var ErrConflict = errors.New("the resource has been changed by someone else")
+
+// hints tell what to do about errors with common statuses
+var hints = map[int]string{
+	http.StatusUnauthorized:    "check the api token, which you can change with the token command or --token",
+	http.StatusForbidden:       "the api token does not have permission to do this, so use a token whose role allows it",
+	http.StatusNotFound:        "check the keys of the project, environment and resource",
+	http.StatusConflict:        "it was changed by someone else after it was read, so read it again and retry",
+	http.StatusTooManyRequests: "too many requests were made, so wait a minute and retry",
+}
+
+// swaggerErrorPattern matches the errors returned by the api client for error responses
+var swaggerErrorPattern = regexp.MustCompile(`(?s)^Status: (\d{3})[^,]*, Body: (.*)$`)
+
+// Error is an error response from the api
+type Error struct {
+	StatusCode int    `json:"status"`
+	Code       string `json:"code,omitempty"`
+	Message    string `json:"message"`
+	// Hint tells what to do about the error
+	Hint string `json:"hint,omitempty"`
+}
+
+// NewError creates an error from the body of an error response, which usually has a code and message
+func NewError(statusCode int, body []byte) *Error {
+	var apiErr Error
+	if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Message == "" {
+		apiErr = Error{Message: strings.TrimSpace(string(body))}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(statusCode)
+	}
+	apiErr.StatusCode = statusCode
+	apiErr.Hint = ""
+	return &apiErr
+}
+
+func (e *Error) Error() string {
+	status := strconv.Itoa(e.StatusCode)
+	if e.Code != "" {
+		status += " " + e.Code
+	}
+	message := fmt.Sprintf("%s (%s)", e.Message, status)
+	if e.Hint != "" {
+		message += ": " + e.Hint
+	}
+	return message
+}
+
+// Is makes a conflict match ErrConflict
+func (e *Error) Is(target error) bool {
+	return target == ErrConflict && e.StatusCode == http.StatusConflict
+}
+
+// TranslateError returns an *Error with a hint for errors from the api, including those returned by the api client and
+// those wrapped with more context.  Errors from the http client are returned without the url of the request, and
+// other errors are returned as they are.
+func TranslateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if urlErr, ok := err.(*url.Error); ok {
+		return TranslateError(urlErr.Err)
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		translated := *apiErr
+		if translated.Hint == "" {
+			translated.Hint = hints[translated.StatusCode]
+		}
+		return &translated
+	}
+	for wrapped := err; wrapped != nil; wrapped = errors.Unwrap(wrapped) {
+		if m := swaggerErrorPattern.FindStringSubmatch(wrapped.Error()); m != nil {
+			statusCode, _ := strconv.Atoi(m[1])
+			translated := NewError(statusCode, []byte(m[2]))
+			translated.Hint = hints[statusCode]
+			return translated
+		}
+	}
+	return err
+}
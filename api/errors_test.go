@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslateError(t *testing.T) {
+	var apiErr *Error
+	err := TranslateError(fmt.Errorf("unable to get flag: %w", NewError(http.StatusNotFound, []byte(`{"code":"not_found","message":"Unknown flag"}`))))
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, Error{StatusCode: http.StatusNotFound, Code: "not_found", Message: "Unknown flag", Hint: hints[http.StatusNotFound]}, *apiErr)
+	assert.Equal(t, "Unknown flag (404 not_found): "+hints[http.StatusNotFound], err.Error())
+
+	err = TranslateError(NewError(http.StatusInternalServerError, []byte("oops")))
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "oops (500)", err.Error())
+
+	err = TranslateError(NewError(http.StatusConflict, nil))
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "Conflict", apiErr.Message)
+	assert.Equal(t, hints[http.StatusConflict], apiErr.Hint)
+	assert.True(t, errors.Is(err, ErrConflict))
+
+	other := errors.New("something else")
+	assert.Equal(t, other, TranslateError(other))
+	assert.Nil(t, TranslateError(nil))
+}
+
+func TestTranslateAPIClientError(t *testing.T) {
+	var apiErr *Error
+	for _, err := range []error{
+		fmt.Errorf("Status: %v, Body: %s", "404 Not Found", `{"code":"not_found","message":"Unknown flag"}`),
+		fmt.Errorf("unable to get flag: %w", fmt.Errorf("Status: %v, Body: %s", "404 Not Found", `{"message":"Unknown flag"}`)),
+	} {
+		translated := TranslateError(err)
+		require.True(t, errors.As(translated, &apiErr), err.Error())
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.Equal(t, "Unknown flag", apiErr.Message)
+		assert.Equal(t, hints[http.StatusNotFound], apiErr.Hint)
+	}
+
+	err := TranslateError(fmt.Errorf("Status: %v, Body: %s", "500 Internal Server Error", "oops"))
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "oops (500)", err.Error())
+}
+
+func TestTranslateHTTPClientError(t *testing.T) {
+	err := TranslateError(&url.Error{Op: "Patch", URL: "https://app.launchdarkly.com/api/v2/flags/p/f", Err: ErrDryRun})
+	assert.Equal(t, ErrDryRun, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"code":"forbidden","message":"Access denied"}`))
+	}))
+	defer server.Close()
+	Initialize("test")
+
+	var result interface{}
+	err = TranslateError(GetJSON(server.URL, "token", "/flags/proj", &result))
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, Error{StatusCode: http.StatusForbidden, Code: "forbidden", Message: "Access denied", Hint: hints[http.StatusForbidden]}, *apiErr)
+
+	client, err := GetClient(server.URL)
+	require.NoError(t, err)
+	_, resp, err := client.FeatureFlagsApi.GetFeatureFlag(GetAuthCtx("token"), "proj", "flag", nil)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	require.True(t, errors.As(TranslateError(err), &apiErr))
+	assert.Equal(t, "Access denied", apiErr.Message)
+}
@@ -6,51 +6,98 @@ import (
 	"time"
 
 	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/pflag"
 	ishell "gopkg.in/abiosoft/ishell.v2"
 
+	ldapi "github.com/launchdarkly/api-client-go"
+
 	"github.com/launchdarkly/ldc/api"
 )
 
 func addAuditLogCommands(shell *ishell.Shell) {
-	root := &ishell.Cmd{
+	root := withFlags(&ishell.Cmd{
 		Name: "log",
-		Help: "search audit log entries",
-		Func: func(c *ishell.Context) {
-			options := make(map[string]interface{})
-			if len(c.Args) > 0 {
-				options["q"] = strings.Join(c.Args, " ")
-				options["limit"] = 5
-				//options["spec"] = ""
-				//options["after"] = 1518163200000
-				//options["before"] = time.Now().UnixNano() / int64(time.Millisecond)
-			}
-			auth := api.GetAuthCtx(getToken(nil))
-			client, err := api.GetClient(getServer(currentConfig))
+		Help: "search audit log entries: log [query] [--all-configs | --configs a,b]",
+		Func: showAuditLog,
+	}, auditLogFlagSet)
+
+	shell.AddCmd(root)
+
+}
+
+func auditLogFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("log", pflag.ContinueOnError)
+	addFanOutFlags(flags)
+	return flags
+}
+
+func auditLogOptions(c *ishell.Context) map[string]interface{} {
+	options := make(map[string]interface{})
+	if len(c.Args) > 0 {
+		options["q"] = strings.Join(c.Args, " ")
+		options["limit"] = 5
+		//options["spec"] = ""
+		//options["after"] = 1518163200000
+		//options["before"] = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+	return options
+}
+
+func formatAuditLogDate(entry ldapi.AuditLogEntry) string {
+	return time.Unix(entry.Date/1000, 0).Format("2006/01/02 15:04:05")
+}
+
+func showAuditLog(c *ishell.Context) {
+	flags := auditLogFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	configs, err := fanOutConfigs(flags)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	if configs != nil {
+		renderForConfigs(c, configs, []string{"Date", "Title"}, func(configKey *string) (configRead, error) {
+			client, err := api.GetClient(getServer(configKey))
 			if err != nil {
-				c.Err(err)
-				return
+				return configRead{}, err
 			}
-			entries, _, err := client.AuditLogApi.GetAuditLogEntries(auth, options)
+			entries, _, err := client.AuditLogApi.GetAuditLogEntries(api.GetAuthCtx(getToken(configKey)), auditLogOptions(c))
 			if err != nil {
-				c.Err(err)
-				return
+				return configRead{}, err
 			}
-			buf := bytes.Buffer{}
-			table := tablewriter.NewWriter(&buf)
-			//table.SetHeader([]string{"Key", "Name"})
+			read := configRead{items: entries.Items}
 			for _, entry := range entries.Items {
-
-				table.Append([]string{time.Unix(entry.Date/1000, 0).Format("2006/01/02 15:04:05"), entry.Title})
-			}
-			table.Render()
-			if buf.Len() > 1000 {
-				c.Err(c.ShowPaged(buf.String()))
-			} else {
-				c.Println(buf.String())
+				read.rows = append(read.rows, []string{formatAuditLogDate(entry), entry.Title})
 			}
-		},
+			return read, nil
+		})
+		return
 	}
 
-	shell.AddCmd(root)
+	auth := api.GetAuthCtx(getToken(nil))
+	client, err := api.GetClient(getServer(currentConfig))
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	entries, _, err := client.AuditLogApi.GetAuditLogEntries(auth, auditLogOptions(c))
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	buf := bytes.Buffer{}
+	table := tablewriter.NewWriter(&buf)
+	//table.SetHeader([]string{"Key", "Name"})
+	for _, entry := range entries.Items {
 
+		table.Append([]string{formatAuditLogDate(entry), entry.Title})
+	}
+	table.Render()
+	if buf.Len() > 1000 {
+		reportError(c, c.ShowPaged(buf.String()))
+	} else {
+		c.Println(buf.String())
+	}
 }
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+
+	"github.com/launchdarkly/ldc/api"
+	"github.com/launchdarkly/ldc/cmd/internal/cache"
+)
+
+// defaultCacheTTL is how long listings are reused by default, which is long enough for completion and short enough
+// that changes made elsewhere soon appear
+const defaultCacheTTL = 30 * time.Second
+
+// resourceCache holds the listings and lookups used for completion and to find resources by name.  Anything that
+// checks the version of a resource before changing it fetches it directly instead.
+var resourceCache = cache.New("")
+
+func cacheDir() (string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "ldc", "cache"), nil
+}
+
+// initCache stores the cache on disk if --disk-cache is set, and forgets everything in it after each change.  The
+// disk cache is cleared after a change even without --disk-cache, so that later commands that use it do not see
+// resources from before the change.
+func initCache() {
+	dir, dirErr := cacheDir()
+	if viper.GetBool("disk-cache") && dirErr == nil {
+		resourceCache = cache.New(dir)
+	}
+	api.AfterWrite = func(*http.Request) {
+		_ = resourceCache.Clear()
+		if dirErr == nil {
+			_ = cache.New(dir).Clear()
+		}
+	}
+}
+
+// cached reads a resource into result from the cache, or calls fetch to read it into result and caches it for
+// --cache-ttl
+func cached(configKey *string, resource string, result interface{}, fetch func() error) error {
+	ttl := viper.GetDuration("cache-ttl")
+	key := cacheKey(configKey, resource)
+	if ttl > 0 && resourceCache.Get(key, result) {
+		return nil
+	}
+	if err := fetch(); err != nil {
+		return err
+	}
+	if ttl > 0 {
+		// a resource that cannot be cached is fetched again next time
+		_ = resourceCache.Set(key, result, ttl)
+	}
+	return nil
+}
+
+// cacheKey includes the server and token, since different tokens may see different resources
+func cacheKey(configKey *string, resource string) string {
+	token := sha256.Sum256([]byte(getToken(configKey)))
+	return fmt.Sprintf("%s %s %s", getServer(configKey), hex.EncodeToString(token[:8]), resource)
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	"github.com/launchdarkly/ldc/api"
+)
+
+// shellOnlyCommands are shell commands that have no cobra command, because cobra or the shell command provide them
+var shellOnlyCommands = []string{"clear", "exit", "help", "shell"}
+
+// commandFlagSets holds the options of the shell commands that parse their own
+var commandFlagSets = make(map[*ishell.Cmd]func() *pflag.FlagSet)
+
+// withFlags records the options a shell command parses, so they are also declared by its cobra command
+func withFlags(cmd *ishell.Cmd, flagSet func() *pflag.FlagSet) *ishell.Cmd {
+	commandFlagSets[cmd] = flagSet
+	return cmd
+}
+
+// addCobraCommands adds a cobra command for each shell command, which gives each one help and typed options when it
+// is run on its own
+func addCobraCommands(parent *cobra.Command, cmds []*ishell.Cmd) {
+	for _, cmd := range cmds {
+		if parent == rootCmd && containsString(shellOnlyCommands, cmd.Name) {
+			continue
+		}
+		parent.AddCommand(newCobraCommand(cmd))
+	}
+}
+
+func newCobraCommand(cmd *ishell.Cmd) *cobra.Command {
+	use, short := splitHelp(cmd)
+	cobraCmd := &cobra.Command{
+		Use:     use,
+		Aliases: cmd.Aliases,
+		Short:   short,
+		Long:    cmd.LongHelp,
+		Run:     runShellCommand,
+	}
+	if cmd.Func == nil {
+		// the command only groups its subcommands
+		cobraCmd.Args = cobra.NoArgs
+	}
+	if flagSet, ok := commandFlagSets[cmd]; ok {
+		cobraCmd.Flags().AddFlagSet(flagSet())
+	}
+	addCobraCommands(cobraCmd, cmd.Children())
+	return cobraCmd
+}
+
+// splitHelp splits the usage from the end of the help for a shell command, as in "do something: [parent] name <arg>"
+func splitHelp(cmd *ishell.Cmd) (use string, short string) {
+	for _, separator := range []string{": ", ".  "} {
+		i := strings.LastIndex(cmd.Help, separator)
+		if i < 0 {
+			continue
+		}
+		words := strings.Fields(cmd.Help[i+len(separator):])
+		for j := 0; j < len(words) && j < 2; j++ {
+			if words[j] == cmd.Name {
+				// cobra takes the name of a command from the start of its usage
+				return strings.Join(words[j:], " "), cmd.Help[:i]
+			}
+		}
+	}
+	return cmd.Name, cmd.Help
+}
+
+// runShellCommand runs a command in the shell.  Shell commands parse their own options, so the shell is given the
+// command line rather than the arguments cobra parsed.
+func runShellCommand(_ *cobra.Command, _ []string) {
+	configureShell(commandShell, false)
+	exitOnError(commandShell.Process(shellArgs(os.Args[1:])...))
+}
+
+// exit statuses, which distinguish the api errors that scripts may want to handle
+const (
+	exitError        = 1
+	exitUnauthorized = 3
+	exitForbidden    = 4
+	exitNotFound     = 5
+	exitConflict     = 6
+	exitRateLimited  = 7
+	exitAPIError     = 8
+)
+
+var apiExitCodes = map[int]int{
+	http.StatusUnauthorized:    exitUnauthorized,
+	http.StatusForbidden:       exitForbidden,
+	http.StatusNotFound:        exitNotFound,
+	http.StatusConflict:        exitConflict,
+	http.StatusTooManyRequests: exitRateLimited,
+}
+
+// reportError reports the error of a command.  The interactive shell prints it as it is, so api errors are
+// translated here to read the same there as when running a single command.  A change that was printed instead of
+// sent because of --dry-run is not an error.
+func reportError(c *ishell.Context, err error) {
+	if errors.Is(err, api.ErrDryRun) {
+		if !renderJSON(c) {
+			c.Println("not sent (dry run)")
+		}
+		return
+	}
+	c.Err(api.TranslateError(err))
+}
+
+// reportedError is an error that a command has already included in its output, so it only sets the exit status
+type reportedError struct {
+	error
+}
+
+func (e reportedError) Unwrap() error {
+	return e.error
+}
+
+// exitOnError exits with a non-zero status if a command failed.  In json mode the error is printed as json.
+func exitOnError(err error) {
+	if err == nil {
+		return
+	}
+	if errors.Is(err, api.ErrDryRun) {
+		// the change was printed instead of made
+		os.Exit(0)
+	}
+	if errors.As(err, &reportedError{}) {
+		os.Exit(exitCode(err))
+	}
+	err = api.TranslateError(err)
+	if viper.GetBool("json") {
+		printErrorJSON(err)
+	} else {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+	}
+	os.Exit(exitCode(err))
+}
+
+func exitCode(err error) int {
+	var apiErr *api.Error
+	if !errors.As(err, &apiErr) {
+		if errors.Is(err, errEditConflict) || errors.Is(err, errRampConflict) {
+			return exitConflict
+		}
+		return exitError
+	}
+	if code, ok := apiExitCodes[apiErr.StatusCode]; ok {
+		return code
+	}
+	return exitAPIError
+}
+
+// printErrorJSON prints an error as {"error": {...}}, with the status, code and hint of api errors
+func printErrorJSON(err error) {
+	var result struct {
+		Error interface{} `json:"error"`
+	}
+	var apiErr *api.Error
+	if errors.As(err, &apiErr) {
+		result.Error = apiErr
+	} else {
+		result.Error = map[string]string{"message": err.Error()}
+	}
+	data, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Println(string(data))
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// completionScripts call back into ldc with the words typed so far, so that resource paths complete as they do in
+// the shell
+var completionScripts = map[string]string{
+	"bash": `# bash completion for {{.}}
+_{{.}}() {
+    local IFS=$'\n'
+    COMPREPLY=($({{.}} --disk-cache __complete "${COMP_WORDS[@]:1:$COMP_CWORD}" 2>/dev/null))
+    if [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == */ ]]; then
+        compopt -o nospace
+    fi
+}
+complete -F _{{.}} {{.}}
+`,
+	"zsh": `#compdef {{.}}
+_{{.}}() {
+    local -a candidates
+    candidates=(${(f)"$({{.}} --disk-cache __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+    compadd -S '' -- ${(M)candidates:#*/}
+    compadd -- ${candidates:#*/}
+}
+compdef _{{.}} {{.}}
+`,
+	"fish": `# fish completion for {{.}}
+function __{{.}}_complete
+    set -l tokens (commandline -opc)
+    set -l current (commandline -ct)
+    {{.}} --disk-cache __complete $tokens[2..-1] "$current" 2>/dev/null
+end
+complete -c {{.}} -f -a '(__{{.}}_complete)'
+`,
+}
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish",
+	Short: "print a script that completes commands and resource paths in your shell",
+	Long: `Print a script that completes commands and resource paths in your shell.  To load it, add one of these to your
+shell's startup file:
+
+  source <(ldc completion bash)
+  source <(ldc completion zsh)
+  ldc completion fish | source`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Run: func(cmd *cobra.Command, args []string) {
+		script, ok := completionScripts[args[0]]
+		if !ok {
+			exitOnError(fmt.Errorf(`unsupported shell "%s": must be bash, zsh or fish`, args[0]))
+		}
+		exitOnError(template.Must(template.New(args[0]).Parse(script)).Execute(os.Stdout, rootCmd.Name()))
+	},
+}
+
+// completeCmd prints the candidates for the last of the words typed so far, one per line, for the completion scripts
+var completeCmd = &cobra.Command{
+	Use:    "__complete",
+	Hidden: true,
+	// global options are parsed before cobra runs, and the words may contain the options of other commands
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, candidate := range completeWords(args) {
+			fmt.Println(candidate)
+		}
+	},
+}
+
+// completeWords returns the candidates for the last of the words, which is the one being typed
+func completeWords(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	prefix := words[len(words)-1]
+	var candidates []string
+	if strings.HasPrefix(prefix, "-") {
+		candidates = flagCandidates(words[:len(words)-1])
+	} else {
+		words = append(shellArgs(words[:len(words)-1]), prefix)
+		if len(words) == 1 {
+			for _, c := range rootCmd.Commands() {
+				if c.IsAvailableCommand() {
+					candidates = append(candidates, c.Name())
+				}
+			}
+		} else {
+			candidates = customCompleter{shell: commandShell}.getSuggestions(words)
+		}
+	}
+
+	var result []string
+	for _, candidate := range candidates {
+		if candidate != "" && strings.HasPrefix(candidate, prefix) {
+			result = append(result, candidate)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// flagCandidates returns the options of the command named by the words
+func flagCandidates(words []string) []string {
+	cmd, _, err := rootCmd.Find(words)
+	if err != nil {
+		cmd = rootCmd
+	}
+	var candidates []string
+	add := func(flag *pflag.Flag) {
+		candidates = append(candidates, "--"+flag.Name)
+	}
+	cmd.LocalFlags().VisitAll(add)
+	cmd.InheritedFlags().VisitAll(add)
+	return candidates
+}
@@ -1,12 +1,16 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/launchdarkly/ldc/api"
 	"github.com/launchdarkly/ldc/cmd/internal/path"
 
 	homedir "github.com/mitchellh/go-homedir"
@@ -24,6 +28,14 @@ type config struct {
 	DefaultProject string // `json:"defaultProject"`
 	// DefaultEnvironment is the initial environment to use
 	DefaultEnvironment string // `json:"defaultEnvironment"`
+	// ProtectedEnvironments are the keys of environments that changes must be confirmed and commented for
+	ProtectedEnvironments []string // `json:"protectedEnvironments,omitempty"`
+	// MaxRolloutStep is the largest percentage of users a single change may move between variations, or 0 for no limit
+	MaxRolloutStep float64 // `json:"maxRolloutStep,omitempty"`
+	// RequireComment requires a comment for every change
+	RequireComment bool // `json:"requireComment,omitempty"`
+	// CommentPattern is a regular expression, such as a ticket id, that every comment must contain
+	CommentPattern string // `json:"commentPattern,omitempty"`
 }
 
 var configFile map[string]config
@@ -70,6 +82,48 @@ func reloadConfigFile() {
 	}
 }
 
+// saveConfigs sets or, given nil, removes configs and writes the config file.  In a dry run, the changes are printed
+// instead.
+func saveConfigs(changes map[string]interface{}) error {
+	if api.DryRun {
+		names := make([]string, 0, len(changes))
+		for name := range changes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Printf("WRITE %s\n", configViper.ConfigFileUsed())
+		for _, name := range names {
+			data, err := json.Marshal(changes[name])
+			if err != nil {
+				return err
+			}
+			// viper writes keys in lower case
+			var fields map[string]interface{}
+			if err := json.Unmarshal(data, &fields); err == nil && fields != nil {
+				lower := make(map[string]interface{}, len(fields))
+				for k, v := range fields {
+					lower[strings.ToLower(k)] = v
+				}
+				data, _ = json.Marshal(lower)
+			}
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, data, "", "  "); err == nil {
+				data = indented.Bytes()
+			}
+			fmt.Printf("%s: %s\n", name, data)
+		}
+		return api.ErrDryRun
+	}
+	for name, value := range changes {
+		configViper.Set(name, value)
+	}
+	if err := configViper.WriteConfig(); err != nil {
+		return err
+	}
+	reloadConfigFile()
+	return nil
+}
+
 func addConfigCommands(shell *ishell.Shell) {
 	root := &ishell.Cmd{
 		Name: "configs",
@@ -138,18 +192,19 @@ func configCompleter(args []string) []string {
 func getConfigArg(c *ishell.Context) (string, *config) {
 	configs, err := listConfigs()
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return "", nil
 	}
 
 	if len(c.Args) == 0 {
 		options, err := listConfigKeys()
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return "", nil
 		}
-		choice := c.MultiChoice(options, "Choose a config")
-		if choice < 0 {
+		choice, err := multiChoice(c, options, "Choose a config")
+		if err != nil {
+			reportError(c, err)
 			return "", nil
 		}
 		config := configs[options[choice]]
@@ -163,14 +218,13 @@ func getConfigArg(c *ishell.Context) (string, *config) {
 		}
 	}
 
-	c.Err(errors.New("config does not exist"))
+	reportError(c, errors.New("config does not exist"))
 	return "", nil
 }
 
 func selectConfig(c *ishell.Context) {
 	name, config := getConfigArg(c)
 	if config == nil {
-		c.Println("Config not found. Settings unchanged.")
 		return
 	}
 	setConfig(name, *config)
@@ -189,19 +243,18 @@ func setConfig(name string, config config) {
 
 func updateConfig(c *ishell.Context) {
 	if len(c.Args) > 1 && len(c.Args) < 4 {
-		c.Err(errTooFewArgs)
+		reportError(c, errTooFewArgs)
 		return
 	}
 
 	if len(c.Args) > 5 {
-		c.Err(errTooManyArgs)
+		reportError(c, errTooManyArgs)
 		return
 	}
 
 	name, config := getConfigArg(c)
 
 	if config == nil {
-		c.Println("Config not found. Settings unchanged.")
 		return
 	}
 
@@ -209,33 +262,33 @@ func updateConfig(c *ishell.Context) {
 
 	if len(c.Args) <= 1 {
 		c.Printf(`API Token (default "%s"): `, config.APIToken)
-		val, err := c.ReadLineErr()
+		val, err := readLine(c)
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return
 		}
 		newConfig.APIToken = ifNotBlank(val, config.APIToken)
 
 		c.Printf(`Default Project (default "%s"): `, config.DefaultProject)
-		val, err = c.ReadLineErr()
+		val, err = readLine(c)
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return
 		}
 		newConfig.DefaultProject = ifNotBlank(val, config.DefaultProject)
 
 		c.Printf(`Default Environment (default "%s"): `, config.DefaultEnvironment)
-		val, err = c.ReadLineErr()
+		val, err = readLine(c)
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return
 		}
 		newConfig.DefaultEnvironment = ifNotBlank(val, config.DefaultEnvironment)
 
 		c.Printf(`Server (leave blank for "%s" or "-" for default): `, config.Server)
-		val, err = c.ReadLineErr()
+		val, err = readLine(c)
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return
 		}
 		if val == "-" {
@@ -253,41 +306,36 @@ func updateConfig(c *ishell.Context) {
 		}
 	}
 
-	configViper.Set(name, newConfig)
-	if err := configViper.WriteConfig(); err != nil {
-		c.Err(err)
+	if err := saveConfigs(map[string]interface{}{name: newConfig}); err != nil {
+		reportError(c, err)
 		return
 	}
-	reloadConfigFile()
 	c.Println("configuration updated")
 }
 
 func removeConfig(c *ishell.Context) {
 	name, config := getConfigArg(c)
 	if config == nil {
-		c.Err(errors.New("config not found"))
 		return
 	}
 	if !confirmDelete(c, "config", name) {
 		return
 	}
-	configViper.Set(name, nil)
-	if err := configViper.WriteConfig(); err != nil {
-		c.Err(err)
+	if err := saveConfigs(map[string]interface{}{name: nil}); err != nil {
+		reportError(c, err)
 		return
 	}
-	reloadConfigFile()
 	c.Println("configuration removed")
 }
 
 func addConfig(c *ishell.Context) {
 	if len(c.Args) > 1 && len(c.Args) < 4 {
-		c.Err(errTooFewArgs)
+		reportError(c, errTooFewArgs)
 		return
 	}
 
 	if len(c.Args) > 5 {
-		c.Err(errTooManyArgs)
+		reportError(c, errTooManyArgs)
 		return
 	}
 
@@ -298,40 +346,40 @@ func addConfig(c *ishell.Context) {
 		name = pickNewConfigName(c)
 	}
 	if strings.TrimSpace(name) == "" {
-		c.Err(errors.New("invalid name"))
+		reportError(c, errors.New("invalid name"))
 		return
 	}
 
 	newConfig := config{}
 	if len(c.Args) <= 1 {
 		c.Printf("API Token: ")
-		val, err := c.ReadLineErr()
+		val, err := readLine(c)
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return
 		}
 		newConfig.APIToken = val
 
 		c.Printf(`Default Project (default "default"): `)
-		val, err = c.ReadLineErr()
+		val, err = readLine(c)
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return
 		}
 		newConfig.DefaultProject = ifNotBlank(val, "default")
 
 		c.Printf(`Default Environment (default "production"): `)
-		val, err = c.ReadLineErr()
+		val, err = readLine(c)
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return
 		}
 		newConfig.DefaultEnvironment = ifNotBlank(val, "production")
 
 		c.Printf("Server (leave blank for default): ")
-		val, err = c.ReadLineErr()
+		val, err = readLine(c)
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return
 		}
 		newConfig.Server = ifNotBlank(val, "")
@@ -346,12 +394,10 @@ func addConfig(c *ishell.Context) {
 		}
 	}
 
-	configViper.Set(name, newConfig)
-	if err := configViper.WriteConfig(); err != nil {
-		c.Err(err)
+	if err := saveConfigs(map[string]interface{}{name: newConfig}); err != nil {
+		reportError(c, err)
 		return
 	}
-	reloadConfigFile()
 	c.Println("configuration added")
 }
 
@@ -359,18 +405,18 @@ func pickNewConfigName(c *ishell.Context) string {
 	var name string
 	for {
 		c.Printf(`New config name: `)
-		val, err := c.ReadLineErr()
+		val, err := readLine(c)
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return ""
 		}
 		name = strings.TrimSpace(val)
 		if name == "" {
-			c.Err(errors.New("must not be blank"))
+			reportError(c, errors.New("must not be blank"))
 			continue
 		}
 		if _, exists := configFile[name]; exists {
-			c.Err(errors.New("config already exists"))
+			reportError(c, errors.New("config already exists"))
 			continue
 		}
 		break
@@ -380,14 +426,14 @@ func pickNewConfigName(c *ishell.Context) string {
 
 func renameConfig(c *ishell.Context) {
 	if len(c.Args) > 2 {
-		c.Err(errTooFewArgs)
+		reportError(c, errTooFewArgs)
 		return
 	}
 
 	name, cfg := getConfigArg(c)
 
 	if cfg == nil {
-		c.Err(errNotFound)
+		reportError(c, errNotFound)
 		return
 	}
 
@@ -398,7 +444,7 @@ func renameConfig(c *ishell.Context) {
 		newName = pickNewConfigName(c)
 	}
 	if strings.TrimSpace(name) == "" {
-		c.Err(errors.New("invalid name"))
+		reportError(c, errors.New("invalid name"))
 		return
 	}
 
@@ -407,16 +453,13 @@ func renameConfig(c *ishell.Context) {
 	}
 
 	if _, exists := configFile[newName]; exists {
-		c.Err(errors.New("target already exists"))
+		reportError(c, errors.New("target already exists"))
 		return
 	}
-	configViper.Set(newName, cfg)
-	configViper.Set(name, nil)
-	if err := configViper.WriteConfig(); err != nil {
-		c.Err(err)
+	if err := saveConfigs(map[string]interface{}{newName: cfg, name: nil}); err != nil {
+		reportError(c, err)
 		return
 	}
-	reloadConfigFile()
 	c.Println("configuration renamed")
 }
 
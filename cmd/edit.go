@@ -0,0 +1,354 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mattbaird/jsonpatch"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+	yaml "gopkg.in/yaml.v2"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+
+	"github.com/launchdarkly/ldc/cmd/internal/merge"
+	"github.com/launchdarkly/ldc/cmd/internal/schema"
+)
+
+// editIndent is the indentation of json opened in the editor
+const editIndent = "    "
+
+// formats for editing resources
+const (
+	editFormatJSON = "json"
+	editFormatYAML = "yaml"
+)
+
+// noVersion is the version of resources that do not have one
+const noVersion = -1
+
+// maxPreviewValue is the longest value shown when previewing a patch
+const maxPreviewValue = 60
+
+// errEditConflict is returned by an editTarget when a patch fails because the resource has changed
+var errEditConflict = errors.New("the resource was changed by someone else")
+
+// editTarget is a resource that can be edited as json
+type editTarget struct {
+	// kind names the resource in messages
+	kind string
+	// definition is the name of the resource's schema in the openapi spec, if it has one
+	definition string
+	// newValue returns a pointer to the api client type of the resource, to check that edits can be decoded
+	newValue func() interface{}
+	// fetch returns the current json of the resource and its version, for merging after a conflict
+	fetch func() ([]byte, int, error)
+	// patch applies a patch, returning errEditConflict if the version test fails
+	patch func(patch ldapi.PatchComment) error
+	// noComment is set for resources whose changes are not recorded with a comment
+	noComment bool
+}
+
+func editFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("edit", pflag.ContinueOnError)
+	flags.Bool("yaml", false, "edit as yaml instead of json")
+	return flags
+}
+
+// confirmEdit asks whether to send, discard or edit the changes again, answering "y" with --yes.  Without a terminal
+// the changes are only sent with --yes.
+func confirmEdit(c *ishell.Context) (string, error) {
+	if viper.GetBool("yes") {
+		return "y", nil
+	}
+	if !canPrompt() {
+		return "", fmt.Errorf("%w: use --yes to send the changes without confirming them", errNoTerminal)
+	}
+	c.Print("Send these changes? [y]/n/e(dit) ")
+	return readLine(c)
+}
+
+// parseEditFlags parses the edit options, returning the format to edit in
+func parseEditFlags(c *ishell.Context) (string, bool) {
+	flags := editFlagSet()
+	if !parseFlags(c, flags) {
+		return "", false
+	}
+	if useYAML, _ := flags.GetBool("yaml"); useYAML {
+		return editFormatYAML, true
+	}
+	return editFormatJSON, true
+}
+
+// editResource edits a resource in the editor, shows the changes and patches it once they are confirmed.  The patch
+// only applies if the resource is still at the given version.  If it has changed, the changes are combined with a
+// three-way merge, and the editor is reopened to resolve any conflicts.  Resources without versions, such as
+// environments, are given noVersion and patched without the check.  It returns false if nothing was changed.
+func editResource(c *ishell.Context, target editTarget, format string, base []byte, version int) (bool, error) {
+	mine, err := editDocument(c, base, format, target.check)
+	if err != nil || mine == nil {
+		return false, err
+	}
+
+	var comment string
+	commentRead := false
+	for {
+		ops, err := jsonpatch.CreatePatch(base, mine)
+		if err != nil {
+			return false, err
+		}
+		if len(ops) == 0 {
+			return false, nil
+		}
+
+		if err := previewPatch(c, target, mine, ops); err != nil {
+			return false, err
+		}
+		answer, err := confirmEdit(c)
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "", "y":
+		case "e":
+			mine, err = editDocument(c, mine, format, target.check)
+			if err != nil || mine == nil {
+				return false, err
+			}
+			continue
+		default:
+			c.Println("Edit aborted")
+			return false, nil
+		}
+
+		if !commentRead && !target.noComment {
+			// without a terminal, the comment is only set with --comment
+			if comment = changeComment(""); comment == "" && canPrompt() {
+				c.Print("Enter comment: ")
+				if comment, err = readLine(c); err != nil {
+					return false, err
+				}
+			}
+			commentRead = true
+		}
+
+		patchComment := ldapi.PatchComment{Comment: comment}
+		if version != noVersion {
+			patchComment.Patch = append(patchComment.Patch, ldapi.PatchOperation{Op: "test", Path: "/_version", Value: interfacePtr(version)})
+		}
+		for _, op := range ops {
+			value := op.Value
+			patchComment.Patch = append(patchComment.Patch, ldapi.PatchOperation{Op: op.Operation, Path: op.Path, Value: &value})
+		}
+		err = target.patch(patchComment)
+		if err != errEditConflict {
+			return err == nil, err
+		}
+
+		c.Printf("The %s was changed by someone else while you were editing it.\n", target.kind)
+		current, currentVersion, err := target.fetch()
+		if err != nil {
+			return false, err
+		}
+		mine, err = mergeEdit(c, target, base, mine, current)
+		if err != nil || mine == nil {
+			return false, err
+		}
+		base, version = current, currentVersion
+	}
+}
+
+// check returns the reasons an edited resource cannot be sent.  Properties missing from the spec are not reported
+// here because the spec is older than the api; they are noted in the preview instead.
+func (t editTarget) check(data []byte) []string {
+	if t.newValue != nil {
+		if err := json.Unmarshal(data, t.newValue()); err != nil {
+			return []string{strings.TrimPrefix(err.Error(), "json: ")}
+		}
+	}
+	if t.definition == "" {
+		return nil
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return []string{err.Error()}
+	}
+	schemaProblems, err := schema.Validate(t.definition, doc)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var problems []string
+	for _, p := range schemaProblems {
+		if !p.Unknown {
+			problems = append(problems, p.String())
+		}
+	}
+	return problems
+}
+
+// previewPatch shows the operations that will be sent, noting any that change read-only properties or properties
+// that are not in the spec
+func previewPatch(c *ishell.Context, target editTarget, mine []byte, ops []jsonpatch.JsonPatchOperation) error {
+	var unknown []string
+	if target.definition != "" {
+		var doc interface{}
+		if err := json.Unmarshal(mine, &doc); err != nil {
+			return err
+		}
+		problems, err := schema.Validate(target.definition, doc)
+		if err != nil {
+			return err
+		}
+		for _, p := range problems {
+			if p.Unknown {
+				unknown = append(unknown, p.Path)
+			}
+		}
+	}
+
+	buf := bytes.Buffer{}
+	table := tablewriter.NewWriter(&buf)
+	table.SetHeader([]string{"Op", "Path", "Value", "Note"})
+	table.SetAutoWrapText(false)
+	readOnly := false
+	for _, op := range ops {
+		var note string
+		switch {
+		case schema.ReadOnly(op.Path):
+			note = "read-only"
+			readOnly = true
+		case withinAny(op.Path, unknown):
+			note = "not in the api spec"
+		}
+		value, err := previewValue(op)
+		if err != nil {
+			return err
+		}
+		table.Append([]string{op.Operation, op.Path, value, note})
+	}
+	table.Render()
+	c.Printf("Changes to the %s:\n", target.kind)
+	c.Print(buf.String())
+	if readOnly {
+		c.Println("Read-only properties are set by LaunchDarkly; changes to them will be rejected or ignored.")
+	}
+	return nil
+}
+
+func previewValue(op jsonpatch.JsonPatchOperation) (string, error) {
+	if op.Operation == "remove" {
+		return "", nil
+	}
+	data, err := json.Marshal(op.Value)
+	if err != nil {
+		return "", err
+	}
+	value := string(data)
+	if len(value) > maxPreviewValue {
+		value = value[:maxPreviewValue-3] + "..."
+	}
+	return value, nil
+}
+
+// withinAny returns true if a json pointer is one of the given pointers or inside one of them
+func withinAny(pointer string, pointers []string) bool {
+	for _, p := range pointers {
+		if pointer == p || strings.HasPrefix(pointer, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeEdit merges my changes with theirs, opening the editor to resolve any conflicts.  Conflicts are always
+// resolved as json.  It returns nil if the edit is aborted.
+func mergeEdit(c *ishell.Context, target editTarget, base, mine, theirs []byte) ([]byte, error) {
+	var baseDoc, mineDoc, theirsDoc interface{}
+	for _, doc := range []struct {
+		data []byte
+		v    *interface{}
+	}{{base, &baseDoc}, {mine, &mineDoc}, {theirs, &theirsDoc}} {
+		if err := json.Unmarshal(doc.data, doc.v); err != nil {
+			return nil, err
+		}
+	}
+
+	merged, conflicts := merge.Merge(baseDoc, mineDoc, theirsDoc)
+	if len(conflicts) == 0 {
+		c.Println("Your changes were merged with theirs.")
+		return json.MarshalIndent(merged, "", editIndent)
+	}
+
+	c.Printf("%d of your changes conflict with theirs:\n", len(conflicts))
+	for _, conflict := range conflicts {
+		c.Printf("  %s\n", conflict.Path)
+	}
+	c.Print("Resolve the conflicts in the editor? [y]/n ")
+	if !yesOrNo(c) {
+		c.Println("Edit aborted")
+		return nil, nil
+	}
+	marked, err := merge.Markup(merged, conflicts, editIndent)
+	if err != nil {
+		return nil, err
+	}
+	return editDocument(c, marked, editFormatJSON, target.check)
+}
+
+// marshalEdit formats a resource for editing
+func marshalEdit(v interface{}) ([]byte, error) {
+	return json.MarshalIndent(v, "", editIndent)
+}
+
+// toEditFormat converts json to the format it is edited in, keeping the order of properties
+func toEditFormat(data []byte, format string) ([]byte, error) {
+	if format != editFormatYAML {
+		return data, nil
+	}
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+// fromEditFormat converts an edited document back to json
+func fromEditFormat(data []byte, format string) ([]byte, error) {
+	if format != editFormatYAML {
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(yamlToJSONValue(doc), "", editIndent)
+}
+
+// yamlToJSONValue replaces the maps decoded from yaml, which can have keys of any type, with maps that can be
+// encoded as json
+func yamlToJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			result[fmt.Sprint(key)] = yamlToJSONValue(value)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, value := range v {
+			result[i] = yamlToJSONValue(value)
+		}
+		return result
+	}
+	return v
+}
@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/launchdarkly/ldc/cmd/internal/path"
 
 	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/pflag"
 	ishell "gopkg.in/abiosoft/ishell.v2"
 
 	ldapi "github.com/launchdarkly/api-client-go"
@@ -15,6 +18,11 @@ import (
 	"github.com/launchdarkly/ldc/api"
 )
 
+// defaultEnvironmentColor is the color of new environments unless another is given or copied
+const defaultEnvironmentColor = "000000"
+
+var colorPattern = regexp.MustCompile(`^[0-9a-fA-F]{6}$`)
+
 func addEnvironmentCommands(shell *ishell.Shell) {
 	root := &ishell.Cmd{
 		Name:    "environments",
@@ -34,12 +42,30 @@ func addEnvironmentCommands(shell *ishell.Shell) {
 		Completer: environmentCompleter,
 		Func:      showEnvironment,
 	})
-	root.AddCmd(&ishell.Cmd{
+	root.AddCmd(withFlags(&ishell.Cmd{
 		Name:    "create",
 		Aliases: []string{"new", "c", "add"},
-		Help:    "create a environment: environment create key [name]",
+		Help:    "create a environment: environment create key [name] [--copy-from environment]",
 		Func:    createEnvironment,
-	})
+	}, createEnvironmentFlagSet))
+	root.AddCmd(withFlags(&ishell.Cmd{
+		Name:      "edit",
+		Help:      "edit an environment as json or yaml: environment edit key",
+		Completer: environmentCompleter,
+		Func:      editEnvironment,
+	}, editFlagSet))
+	root.AddCmd(withFlags(&ishell.Cmd{
+		Name:      "set",
+		Help:      "change settings of an environment: environment set key --color ff0000 --secure-mode",
+		Completer: environmentCompleter,
+		Func:      setEnvironment,
+	}, setEnvironmentFlagSet))
+	root.AddCmd(withFlags(&ishell.Cmd{
+		Name:      "keys",
+		Help:      "export the sdk keys of an environment: environment keys key --format dotenv|json|k8s-secret|shell [--reveal]",
+		Completer: environmentCompleter,
+		Func:      showEnvironmentKeys,
+	}, keysFlagSet))
 	root.AddCmd(&ishell.Cmd{
 		Name:      "delete",
 		Aliases:   []string{"remove", "d", "del", "rm"},
@@ -52,16 +78,21 @@ func addEnvironmentCommands(shell *ishell.Shell) {
 }
 
 func listEnvironments(configKey *string, projKey string) ([]ldapi.Environment, error) {
-	client, err := api.GetClient(getServer(configKey))
-	if err != nil {
-		return nil, err
-	}
-	auth := api.GetAuthCtx(getToken(configKey))
-	project, _, err := client.ProjectsApi.GetProject(auth, projKey)
+	var environments []ldapi.Environment
+	err := cached(configKey, fmt.Sprintf("projects/%s/environments", projKey), &environments, func() error {
+		client, err := api.GetClient(getServer(configKey))
+		if err != nil {
+			return err
+		}
+		auth := api.GetAuthCtx(getToken(configKey))
+		project, _, err := client.ProjectsApi.GetProject(auth, projKey)
+		environments = project.Environments
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	return project.Environments, nil
+	return environments, nil
 }
 
 func listEnvironmentKeys(configKey *string, project string) (keys []string, err error) {
@@ -82,13 +113,13 @@ func showEnvironments(c *ishell.Context) {
 func showEnvironmentsForProject(c *ishell.Context, projPath projPath) {
 	client, err := api.GetClient(getServer(projPath.Config()))
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	auth := api.GetAuthCtx(getToken(projPath.Config()))
 	project, _, err := client.ProjectsApi.GetProject(auth, projPath.Key())
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 
@@ -107,7 +138,7 @@ func showEnvironmentsForProject(c *ishell.Context, projPath projPath) {
 	table.SetRowLine(true)
 	table.Render()
 	if buf.Len() > 1000 {
-		c.Err(c.ShowPaged(buf.String()))
+		reportError(c, c.ShowPaged(buf.String()))
 	} else {
 		c.Print(buf.String())
 	}
@@ -117,7 +148,6 @@ func showEnvironment(c *ishell.Context) {
 	envPath, env := getEnvironmentArg(c)
 
 	if env == nil {
-		c.Println("Environment not found")
 		return
 	}
 
@@ -128,14 +158,14 @@ func showEnvironment(c *ishell.Context) {
 
 	client, err := api.GetClient(getServer(envPath.Config()))
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	auth := api.GetAuthCtx(getToken(envPath.Config()))
 
 	project, _, err := client.ProjectsApi.GetProject(auth, currentProject)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 
@@ -175,18 +205,18 @@ func getEnvironmentArg(c *ishell.Context) (perProjectPath, *ldapi.Environment) {
 	if len(c.Args) > 0 {
 		realPath, err := realEnvPath(c.Args[0])
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return perProjectPath{}, nil
 		}
 		auth := api.GetAuthCtx(getToken(realPath.Config()))
 		client, err := api.GetClient(getServer(realPath.Config()))
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return perProjectPath{}, nil
 		}
 		env, _, err := client.EnvironmentsApi.GetEnvironment(auth, realPath.Project(), realPath.Key())
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return perProjectPath{}, nil
 		}
 		return realPath, &env
@@ -194,12 +224,12 @@ func getEnvironmentArg(c *ishell.Context) (perProjectPath, *ldapi.Environment) {
 
 	env, err := chooseEnvironment(c, currentConfig, currentProject)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return perProjectPath{}, nil
 	}
 	realPath, err := realEnvPath(env.Key)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return perProjectPath{}, nil
 	}
 	return realPath, env
@@ -222,25 +252,107 @@ func chooseEnvironment(c *ishell.Context, config *string, project string) (*ldap
 	}
 
 	options := keysForEnvironments(environments)
-	choice := c.MultiChoice(options, "Choose an environment")
-	if choice < 0 {
-		return nil, nil
+	choice, err := multiChoice(c, options, "Choose an environment")
+	if err != nil {
+		return nil, err
 	}
 	return &environments[choice], nil
 }
 
+func environmentSettingsFlagSet(name string) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.String("name", "", "name of the environment")
+	flags.String("color", "", "color of the environment as six hex digits, e.g. ff0000")
+	flags.Int("default-ttl", 0, "minutes that client-side SDKs may cache flag values")
+	flags.Bool("secure-mode", false, "require client-side SDKs to send a hash of the user key")
+	flags.Bool("default-track-events", false, "send detailed events for new flags")
+	addTagFlags(flags)
+	return flags
+}
+
+func createEnvironmentFlagSet() *pflag.FlagSet {
+	flags := environmentSettingsFlagSet("create")
+	flags.String("copy-from", "", "environment to copy the color, ttl, secure mode, track events and tags of")
+	return flags
+}
+
+func setEnvironmentFlagSet() *pflag.FlagSet {
+	return environmentSettingsFlagSet("set")
+}
+
+// applyEnvironmentSettings changes the settings of env that were given as options
+func applyEnvironmentSettings(flags *pflag.FlagSet, env *ldapi.Environment) error {
+	if flags.Changed("name") {
+		env.Name, _ = flags.GetString("name")
+	}
+	if flags.Changed("color") {
+		color, _ := flags.GetString("color")
+		color = strings.TrimPrefix(color, "#")
+		if !colorPattern.MatchString(color) {
+			return fmt.Errorf(`invalid color "%s": use six hex digits, e.g. ff0000`, color)
+		}
+		env.Color = strings.ToLower(color)
+	}
+	if flags.Changed("default-ttl") {
+		ttl, _ := flags.GetInt("default-ttl")
+		if ttl < 0 {
+			return errors.New("the default ttl cannot be negative")
+		}
+		env.DefaultTtl = float32(ttl)
+	}
+	if flags.Changed("secure-mode") {
+		env.SecureMode, _ = flags.GetBool("secure-mode")
+	}
+	if flags.Changed("default-track-events") {
+		env.DefaultTrackEvents, _ = flags.GetBool("default-track-events")
+	}
+	env.Tags = applyTagFlags(flags, env.Tags)
+	return nil
+}
+
+// environmentPatch returns the changes to the settings of an environment
+func environmentPatch(before, after ldapi.Environment) []ldapi.PatchOperation {
+	var patch []ldapi.PatchOperation
+	replace := func(path string, value interface{}) {
+		patch = append(patch, ldapi.PatchOperation{Op: "replace", Path: path, Value: interfacePtr(value)})
+	}
+	if after.Name != before.Name {
+		replace("/name", after.Name)
+	}
+	if after.Color != before.Color {
+		replace("/color", after.Color)
+	}
+	if after.DefaultTtl != before.DefaultTtl {
+		replace("/defaultTtl", after.DefaultTtl)
+	}
+	if after.SecureMode != before.SecureMode {
+		replace("/secureMode", after.SecureMode)
+	}
+	if after.DefaultTrackEvents != before.DefaultTrackEvents {
+		replace("/defaultTrackEvents", after.DefaultTrackEvents)
+	}
+	if strings.Join(after.Tags, ",") != strings.Join(before.Tags, ",") {
+		replace("/tags", after.Tags)
+	}
+	return patch
+}
+
 func createEnvironment(c *ishell.Context) {
+	flags := createEnvironmentFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
 	var name string
 	var p perProjectPath
 	switch len(c.Args) {
 	case 0:
-		c.Err(errors.New("please supply at least a key for the new environment"))
+		reportError(c, errors.New("please supply at least a key for the new environment"))
 		return
 	case 1, 2:
 		var err error
 		p, err = realEnvPath(c.Args[0])
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return
 		}
 		if len(c.Args) > 1 {
@@ -249,21 +361,57 @@ func createEnvironment(c *ishell.Context) {
 			name = p.Key()
 		}
 	default:
-		c.Err(errors.New(`expected arguments are "key [name]""`))
+		reportError(c, errors.New(`expected arguments are "key [name]"`))
 		return
 	}
 	client, err := api.GetClient(getServer(p.Config()))
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	key := p.Key()
 	auth := api.GetAuthCtx(getToken(p.Config()))
-	_, err = client.EnvironmentsApi.PostEnvironment(auth, p.Project(), ldapi.EnvironmentPost{Key: key, Name: name, Color: "000000"})
+
+	env := ldapi.Environment{Key: key, Name: name, Color: defaultEnvironmentColor}
+	if copyFrom, _ := flags.GetString("copy-from"); copyFrom != "" {
+		fromPath, err := realEnvPath(copyFrom)
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		fromClient, err := api.GetClient(getServer(fromPath.Config()))
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		fromAuth := api.GetAuthCtx(getToken(fromPath.Config()))
+		from, _, err := fromClient.EnvironmentsApi.GetEnvironment(fromAuth, fromPath.Project(), fromPath.Key())
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		env.Color, env.DefaultTtl, env.SecureMode, env.DefaultTrackEvents, env.Tags =
+			from.Color, from.DefaultTtl, from.SecureMode, from.DefaultTrackEvents, from.Tags
+	}
+	if err := applyEnvironmentSettings(flags, &env); err != nil {
+		reportError(c, err)
+		return
+	}
+
+	post := ldapi.EnvironmentPost{Key: key, Name: env.Name, Color: env.Color, DefaultTtl: env.DefaultTtl}
+	_, err = client.EnvironmentsApi.PostEnvironment(auth, p.Project(), post)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
+	// the other settings cannot be given when an environment is created
+	created := ldapi.Environment{Key: key, Name: post.Name, Color: post.Color, DefaultTtl: post.DefaultTtl}
+	if patch := environmentPatch(created, env); len(patch) > 0 {
+		if _, _, err := client.EnvironmentsApi.PatchEnvironment(auth, p.Project(), key, patch); err != nil {
+			reportError(c, err)
+			return
+		}
+	}
 	if isInteractive(c) {
 		c.Printf("Created environment %s\n", key)
 		c.Printf("Switching to environment %s\n", key)
@@ -271,6 +419,126 @@ func createEnvironment(c *ishell.Context) {
 	currentEnvironment = key
 }
 
+func setEnvironment(c *ishell.Context) {
+	flags := setEnvironmentFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	if len(c.Args) > 1 {
+		reportError(c, errTooManyArgs)
+		return
+	}
+	envPath, env := getEnvironmentArg(c)
+	if env == nil {
+		return
+	}
+	changed := *env
+	if err := applyEnvironmentSettings(flags, &changed); err != nil {
+		reportError(c, err)
+		return
+	}
+	patch := environmentPatch(*env, changed)
+	if len(patch) == 0 {
+		reportError(c, errors.New("nothing to change: use --name, --color, --default-ttl, --secure-mode, --default-track-events or the tag options"))
+		return
+	}
+	if err := confirmChange(c, envPath.Config(), []string{envPath.Key()}); err != nil {
+		reportError(c, err)
+		return
+	}
+	client, err := api.GetClient(getServer(envPath.Config()))
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	auth := api.GetAuthCtx(getToken(envPath.Config()))
+	updated, _, err := client.EnvironmentsApi.PatchEnvironment(auth, envPath.Project(), envPath.Key(), patch)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	if renderJSON(c) {
+		printJSON(c, updated)
+		return
+	}
+	c.Printf("Updated environment %s\n", envPath.Key())
+}
+
+// getEnvironmentJSON gets an environment as the api returns it, because the api client leaves out false and empty
+// properties such as secureMode.  The id, links and sdk keys are left out because they cannot be edited.
+func getEnvironmentJSON(envPath perProjectPath) (map[string]interface{}, error) {
+	var env map[string]interface{}
+	err := api.GetJSON(getServer(envPath.Config()), getToken(envPath.Config()),
+		fmt.Sprintf("/projects/%s/environments/%s", envPath.Project(), envPath.Key()), &env)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"_id", "_links", "apiKey", "mobileKey"} {
+		delete(env, key)
+	}
+	return env, nil
+}
+
+func editEnvironment(c *ishell.Context) {
+	format, ok := parseEditFlags(c)
+	if !ok {
+		return
+	}
+	envPath, env := getEnvironmentArg(c)
+	if env == nil {
+		return
+	}
+	current, err := getEnvironmentJSON(envPath)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	data, err := marshalEdit(current)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	client, err := api.GetClient(getServer(envPath.Config()))
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	auth := api.GetAuthCtx(getToken(envPath.Config()))
+
+	changed, err := editResource(c, editTarget{
+		kind:       "environment",
+		definition: "Environment",
+		newValue:   func() interface{} { return &ldapi.Environment{} },
+		fetch: func() ([]byte, int, error) {
+			current, err := getEnvironmentJSON(envPath)
+			if err != nil {
+				return nil, 0, err
+			}
+			data, err := marshalEdit(current)
+			return data, noVersion, err
+		},
+		patch: func(patchComment ldapi.PatchComment) error {
+			if err := confirmChange(c, envPath.Config(), []string{envPath.Key()}); err != nil {
+				return err
+			}
+			_, _, err := client.EnvironmentsApi.PatchEnvironment(auth, envPath.Project(), envPath.Key(), patchComment.Patch)
+			return err
+		},
+		noComment: true,
+	}, format, data, noVersion)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+
+	if !changed {
+		c.Println("No changes")
+		return
+	}
+
+	c.Println("Updated environment")
+}
+
 func deleteEnvironment(c *ishell.Context) {
 	envPath, environment := getEnvironmentArg(c)
 	if environment == nil {
@@ -281,13 +549,13 @@ func deleteEnvironment(c *ishell.Context) {
 	}
 	client, err := api.GetClient(getServer(envPath.Config()))
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	auth := api.GetAuthCtx(getToken(envPath.Config()))
 	_, err = client.EnvironmentsApi.DeleteEnvironment(auth, envPath.Project(), environment.Key)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	c.Printf("Deleted environment %s\n", environment.Key)
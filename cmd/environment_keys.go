@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+
+	"github.com/launchdarkly/ldc/cmd/internal/path"
+	"github.com/launchdarkly/ldc/cmd/internal/sdkkeys"
+)
+
+func keysFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("keys", pflag.ContinueOnError)
+	flags.String("format", sdkkeys.FormatDotenv, "output format: "+strings.Join(sdkkeys.Formats, ", "))
+	flags.Bool("reveal", false, "show the sdk and mobile keys instead of masking them")
+	flags.Bool("all", false, "export every environment of the project given instead of one environment")
+	return flags
+}
+
+func showEnvironmentKeys(c *ishell.Context) {
+	flags := keysFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	format, _ := flags.GetString("format")
+	reveal, _ := flags.GetBool("reveal")
+	all, _ := flags.GetBool("all")
+	if renderJSON(c) {
+		format = sdkkeys.FormatJSON
+	}
+	if len(c.Args) > 1 {
+		reportError(c, errTooManyArgs)
+		return
+	}
+
+	var environments []ldapi.Environment
+	if all {
+		p := projPath{path.NewAbsPath(currentConfig, currentProject)}
+		if len(c.Args) > 0 {
+			var err error
+			if p, err = realProjPath(c.Args[0]); err != nil {
+				reportError(c, err)
+				return
+			}
+		}
+		var err error
+		if environments, err = listEnvironments(p.Config(), p.Key()); err != nil {
+			reportError(c, err)
+			return
+		}
+		if len(environments) == 0 {
+			reportError(c, fmt.Errorf("project %s has no environments", p.Key()))
+			return
+		}
+	} else {
+		_, env := getEnvironmentArg(c)
+		if env == nil {
+			return
+		}
+		environments = []ldapi.Environment{*env}
+	}
+
+	var keys []sdkkeys.Keys
+	for _, env := range environments {
+		k := sdkkeys.Keys{Environment: env.Key, SDKKey: env.ApiKey, MobileKey: env.MobileKey, ClientSideID: env.Id}
+		if !reveal {
+			k = k.Masked()
+		}
+		keys = append(keys, k)
+	}
+	out, err := sdkkeys.Format(keys, format)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	c.Print(out)
+	if !reveal {
+		// on stderr so that it does not end up in a file the keys are written to
+		fmt.Fprintln(os.Stderr, "The sdk and mobile keys are masked; use --reveal to show them")
+	}
+}
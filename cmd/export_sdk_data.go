@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+
+	"github.com/spf13/pflag"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	"github.com/launchdarkly/ldc/api"
+	"github.com/launchdarkly/ldc/cmd/internal/sdkdata"
+)
+
+func addExportSDKDataCommands(shell *ishell.Shell) {
+	shell.AddCmd(withFlags(&ishell.Cmd{
+		Name:      "export-sdk-data",
+		Help:      "export the flags and segments of an environment for an sdk file data source: export-sdk-data [/project/env] [--flag-values] [--output file]",
+		Func:      exportSDKData,
+		Completer: environmentCompleter,
+	}, exportSDKDataFlagSet))
+}
+
+func exportSDKDataFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("export-sdk-data", pflag.ContinueOnError)
+	flags.Bool("flag-values", false, "only export the value most users currently receive from each flag")
+	flags.String("output", "", "file to write to instead of standard output")
+	return flags
+}
+
+func exportSDKData(c *ishell.Context) {
+	flags := exportSDKDataFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	flagValues, _ := flags.GetBool("flag-values")
+	output, _ := flags.GetString("output")
+
+	if len(c.Args) > 1 {
+		reportError(c, errTooManyArgs)
+		return
+	}
+	rawPath := ""
+	if len(c.Args) == 1 {
+		rawPath = c.Args[0]
+	}
+	envPath, err := realEnvPath(rawPath)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+
+	data, err := getSDKData(envPath)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+
+	var export interface{} = data
+	if flagValues {
+		export = sdkdata.ToFlagValues(*data)
+	}
+
+	if output == "" {
+		printJSON(c, export)
+		return
+	}
+	bytes, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	if err := ioutil.WriteFile(output, append(bytes, '\n'), 0644); err != nil {
+		reportError(c, err)
+		return
+	}
+	if !renderJSON(c) {
+		c.Printf("Exported %d flags and %d segments to %s\n", len(data.Flags), len(data.Segments), output)
+	}
+}
+
+// getSDKData fetches all flags and segments of an environment in the format used by the sdks.  They are read from the
+// json of the api because the api client drops null off variations, salts and the attributes rollouts bucket by.
+func getSDKData(envPath perProjectPath) (*sdkdata.Data, error) {
+	server, token := getServer(envPath.Config()), getToken(envPath.Config())
+
+	var flags struct {
+		Items []sdkdata.APIFlag `json:"items"`
+	}
+	err := api.GetJSON(server, token, fmt.Sprintf("/flags/%s?env=%s", envPath.Project(), url.QueryEscape(envPath.Key())), &flags)
+	if err != nil {
+		return nil, err
+	}
+	var segments struct {
+		Items []sdkdata.APISegment `json:"items"`
+	}
+	err = api.GetJSON(server, token, fmt.Sprintf("/segments/%s/%s", envPath.Project(), envPath.Key()), &segments)
+	if err != nil {
+		return nil, err
+	}
+
+	data := sdkdata.Data{
+		Flags:    make(map[string]sdkdata.Flag),
+		Segments: make(map[string]sdkdata.Segment),
+	}
+	for _, flag := range flags.Items {
+		if f, ok := sdkdata.FromAPIFlag(flag, envPath.Key()); ok {
+			data.Flags[f.Key] = f
+		}
+	}
+	for _, segment := range segments.Items {
+		data.Segments[segment.Key] = sdkdata.FromAPISegment(segment)
+	}
+	return &data, nil
+}
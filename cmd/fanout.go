@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/pflag"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	"github.com/launchdarkly/ldc/cmd/internal/fanout"
+)
+
+// maxConcurrentConfigs limits how many configs are read at once, to stay within the rate limits of shared accounts
+const maxConcurrentConfigs = 4
+
+// configResult is what a read command found for one config
+type configResult struct {
+	Config string      `json:"config"`
+	Items  interface{} `json:"items,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// configRead is the result of reading one config, as json items and table rows
+type configRead struct {
+	items interface{}
+	rows  [][]string
+}
+
+func addFanOutFlags(flags *pflag.FlagSet) {
+	flags.Bool("all-configs", false, "read every config at once and merge the results with a config column")
+	flags.StringSlice("configs", nil, "read these comma-separated configs at once and merge the results with a config column")
+}
+
+// fanOutConfigs returns the configs to read for --all-configs or --configs, or nil to read only the current one
+func fanOutConfigs(flags *pflag.FlagSet) ([]string, error) {
+	all, _ := flags.GetBool("all-configs")
+	requested, _ := flags.GetStringSlice("configs")
+	if !all && len(requested) == 0 {
+		return nil, nil
+	}
+	available, err := listConfigKeys()
+	if err != nil {
+		return nil, err
+	}
+	return fanout.Select(available, requested, all)
+}
+
+// defaultProject returns the default project of a config, for reads that are not given one
+func defaultProject(configKey string) (string, error) {
+	project := configFile[configKey].DefaultProject
+	if project == "" {
+		return "", fmt.Errorf("config %s has no default project", configKey)
+	}
+	return project, nil
+}
+
+// renderForConfigs reads every config at once and shows the results together, with the config in the first column.
+// Configs that cannot be read are reported after the others are shown.  In json mode their errors are included with
+// the results instead.
+func renderForConfigs(c *ishell.Context, configs []string, header []string, read func(configKey *string) (configRead, error)) {
+	results := fanout.Run(configs, maxConcurrentConfigs, func(config string) (interface{}, error) {
+		return read(&config)
+	})
+
+	if renderJSON(c) {
+		output := make([]configResult, len(results))
+		for i, r := range results {
+			output[i] = configResult{Config: r.Config}
+			if r.Err != nil {
+				output[i].Error = r.Err.Error()
+			} else {
+				output[i].Items = r.Value.(configRead).items
+			}
+		}
+		printJSON(c, output)
+		if err := fanout.Errors(results); err != nil {
+			// the errors are in the output, so only the exit status is left to set
+			reportError(c, reportedError{err})
+		}
+		return
+	}
+
+	buf := bytes.Buffer{}
+	table := tablewriter.NewWriter(&buf)
+	table.SetHeader(append([]string{"Config"}, header...))
+	for _, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		for _, row := range r.Value.(configRead).rows {
+			table.Append(append([]string{r.Config}, row...))
+		}
+	}
+	table.Render()
+	renderPagedTable(c, buf)
+	if err := fanout.Errors(results); err != nil {
+		reportError(c, err)
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/pflag"
 	ishell "gopkg.in/abiosoft/ishell.v2"
 
 	ldapi "github.com/launchdarkly/api-client-go"
@@ -20,19 +23,19 @@ import (
 
 func addFlagCommands(shell *ishell.Shell) {
 
-	root := &ishell.Cmd{
+	root := withFlags(&ishell.Cmd{
 		Name:    "flags",
 		Aliases: []string{"flag"},
 		Help:    "list and operate on flags",
 		Func:    showFlags,
-	}
-	root.AddCmd(&ishell.Cmd{
+	}, listFlagsFlagSet)
+	root.AddCmd(withFlags(&ishell.Cmd{
 		Name:      "list",
-		Help:      "list flags",
+		Help:      "list flags: list [project] [--all-configs | --configs a,b]",
 		Aliases:   []string{"ls", "l", "show"},
 		Completer: flagCompleter,
 		Func:      showFlags,
-	})
+	}, listFlagsFlagSet))
 	root.AddCmd(&ishell.Cmd{
 		Name:      "show",
 		Help:      "show",
@@ -75,24 +78,24 @@ func addFlagCommands(shell *ishell.Shell) {
 		Completer: flagEnvCompleter,
 		Func:      off,
 	})
-	root.AddCmd(&ishell.Cmd{
+	root.AddCmd(withFlags(&ishell.Cmd{
 		Name:      "rollout",
-		Help:      "set the rollout for a flag.  rollout [N:][name:][variation 0 %] [N:][name:][variation 1 %] ...",
+		Help:      "set the rollout for a flag.  rollout [--rule index|_id] [--bucket-by attribute] [N:][name:][variation 0 %] [N:][name:][variation 1 %] ...",
 		Completer: rolloutCompleter,
 		Func:      rollout,
-	})
+	}, rolloutFlagSet))
 	root.AddCmd(&ishell.Cmd{
 		Name:      "fallthrough",
 		Help:      "set the fallthrough value for a flag.  fallthrough <index> ...",
 		Completer: fallthruCompleter,
 		Func:      fallthru,
 	})
-	root.AddCmd(&ishell.Cmd{
+	root.AddCmd(withFlags(&ishell.Cmd{
 		Name:      "edit",
-		Help:      "edit a flag's json in a text editor",
+		Help:      "edit a flag's json in a text editor.  edit [--yaml] <flag>",
 		Completer: flagCompleter,
 		Func:      editFlag,
-	})
+	}, editFlagSet))
 	root.AddCmd(&ishell.Cmd{
 		Name:      "delete",
 		Aliases:   []string{"remove"},
@@ -100,52 +103,26 @@ func addFlagCommands(shell *ishell.Shell) {
 		Completer: flagCompleter,
 		Func:      deleteFlag,
 	})
-	root.AddCmd(&ishell.Cmd{
+	root.AddCmd(withFlags(&ishell.Cmd{
 		Name:      "status",
-		Help:      "show flag's statuses",
+		Help:      "show flag's statuses: status [flag] [--all-configs | --configs a,b]",
 		Completer: flagEnvCompleter,
-		Func: func(c *ishell.Context) {
-			if len(c.Args) > 0 {
-				flagPath, flag := getFlagConfigArg(c, 0)
-				if flag == nil {
-					return
-				}
-				auth := api.GetAuthCtx(getToken(flagPath.Config()))
-				client, err := api.GetClient(getServer(flagPath.Config()))
-				if err != nil {
-					c.Err(err)
-					return
-				}
-				status, _, err := client.FeatureFlagsApi.GetFeatureFlagStatus(auth, flagPath.Project(), flagPath.Environment(), flagPath.Key())
-				if err != nil {
-					c.Err(err)
-					return
-				}
-				c.Println("Status: " + status.Name)
-				c.Printf("Last Requested: %v\n", status.LastRequested)
-			} else {
-				auth := api.GetAuthCtx(getToken(currentConfig))
-				client, err := api.GetClient(getServer(currentConfig))
-				if err != nil {
-					c.Err(err)
-					return
-				}
-				statuses, _, err := client.FeatureFlagsApi.GetFeatureFlagStatuses(auth, currentProject, currentEnvironment)
-				if err != nil {
-					c.Err(err)
-					return
-				}
-				buf := bytes.Buffer{}
-				table := tablewriter.NewWriter(&buf)
-				table.SetHeader([]string{"Status", "Last Requested"})
-				for _, status := range statuses.Items {
-					table.Append([]string{status.Name, status.LastRequested})
-				}
-				table.Render()
-				c.Println(buf.String())
-			}
-		},
-	})
+		Func:      showFlagStatuses,
+	}, flagStatusFlagSet))
+	root.AddCmd(withFlags(&ishell.Cmd{
+		Name:      "eval",
+		Aliases:   []string{"evaluate"},
+		Help:      "evaluate a flag for a user locally: eval <flag> --user '{\"key\":\"user-key\"}'",
+		Completer: flagEnvCompleter,
+		Func:      evalFlag,
+	}, evalFlagSet))
+	root.AddCmd(withFlags(&ishell.Cmd{
+		Name:      "stale",
+		Help:      "report stale and unused flags across all environments: stale [project] [--format table|csv|markdown] [--candidates] [--refs dir]",
+		Completer: projectCompleter,
+		Func:      showStaleFlags,
+	}, staleFlagSet))
+	addRampCommands(root)
 
 	shell.AddCmd(root)
 }
@@ -220,9 +197,8 @@ var envLister = path.ListerFunc(func(parentPath path.ResourcePath) ([]string, er
 	return options, err
 })
 
-var configLister = path.ListerFunc(func(path path.ResourcePath) (configs []string, err error) {
-	configs = append(configs, path.Keys()...)
-	return configs, nil
+var configLister = path.ListerFunc(func(path.ResourcePath) ([]string, error) {
+	return listConfigKeys()
 })
 
 func flagCompleter(args []string) (completions []string) {
@@ -252,7 +228,7 @@ func getFlagArg(c *ishell.Context, pos int) (perProjectPath, *ldapi.FeatureFlag)
 	} else {
 		flagKey, err := chooseFlag(c, currentConfig, currentProject)
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return perProjectPath{}, nil
 		}
 		pathArg = flagKey
@@ -260,13 +236,13 @@ func getFlagArg(c *ishell.Context, pos int) (perProjectPath, *ldapi.FeatureFlag)
 
 	realPath, err := realFlagPath(pathArg)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return perProjectPath{}, nil
 	}
 
 	flag, err := getFlag(realPath)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return perProjectPath{}, nil
 	}
 	return realPath, flag
@@ -277,9 +253,9 @@ func chooseFlag(c *ishell.Context, config *string, project string) (string, erro
 	if err != nil {
 		return "", err
 	}
-	choice := c.MultiChoice(options, "Choose a flag: ")
-	if choice < 0 {
-		return "", errAborted
+	choice, err := multiChoice(c, options, "Choose a flag: ")
+	if err != nil {
+		return "", err
 	}
 	return options[choice], nil
 }
@@ -291,7 +267,7 @@ func getFlagConfigArg(c *ishell.Context, pos int) (perEnvironmentPath, *ldapi.Fe
 	} else {
 		flagKey, err := chooseFlag(c, currentConfig, currentProject)
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return perEnvironmentPath{}, nil
 		}
 		pathArg = flagKey
@@ -299,13 +275,13 @@ func getFlagConfigArg(c *ishell.Context, pos int) (perEnvironmentPath, *ldapi.Fe
 
 	realPath, err := realFlagConfigPath(pathArg)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return perEnvironmentPath{}, nil
 	}
 
 	flag, err := getFlag(realPath.PerProjectPath())
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return perEnvironmentPath{}, nil
 	}
 	return realPath, flag
@@ -326,18 +302,39 @@ func getFlag(p perProjectPath) (*ldapi.FeatureFlag, error) {
 }
 
 func listFlags(configKey *string, projKey string) ([]ldapi.FeatureFlag, error) {
-	auth := api.GetAuthCtx(getToken(configKey))
-
-	client, err := api.GetClient(getServer(configKey))
+	var flags []ldapi.FeatureFlag
+	err := cached(configKey, "flags/"+projKey, &flags, func() error {
+		auth := api.GetAuthCtx(getToken(configKey))
+		client, err := api.GetClient(getServer(configKey))
+		if err != nil {
+			return err
+		}
+		result, _, err := client.FeatureFlagsApi.GetFeatureFlags(auth, projKey, nil)
+		flags = result.Items
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	return flags, nil
+}
 
-	flags, _, err := client.FeatureFlagsApi.GetFeatureFlags(auth, projKey, nil)
+// getCachedFlag gets a flag for completion, which may be out of date
+func getCachedFlag(configKey *string, projKey string, key string) (*ldapi.FeatureFlag, error) {
+	var flag ldapi.FeatureFlag
+	err := cached(configKey, fmt.Sprintf("flags/%s/%s", projKey, key), &flag, func() error {
+		auth := api.GetAuthCtx(getToken(configKey))
+		client, err := api.GetClient(getServer(configKey))
+		if err != nil {
+			return err
+		}
+		flag, _, err = client.FeatureFlagsApi.GetFeatureFlag(auth, projKey, key, nil)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	return flags.Items, nil
+	return &flag, nil
 }
 
 func getToken(configKey *string) string {
@@ -373,15 +370,52 @@ func listFlagKeys(configKey *string, projKey string) ([]string, error) {
 }
 
 func showFlag(c *ishell.Context) {
-	_, flag := getFlagArg(c, 0)
+	flagPath, flag := getFlagArg(c, 0)
 	if flag == nil {
-		c.Err(errors.New("flag not found"))
 		return
 	}
-	renderFlag(c, *flag)
+	renderFlag(c, *flag, showFlagBucketing(c, flagPath))
+}
+
+// showFlagBucketing fetches the bucketing attributes of a flag's rollouts for display, ignoring errors
+func showFlagBucketing(c *ishell.Context, flagPath perProjectPath) *flagBucketing {
+	if renderJSON(c) {
+		return nil
+	}
+	bucketing, err := getFlagBucketing(flagPath)
+	if err != nil {
+		return nil
+	}
+	return bucketing
+}
+
+func listFlagsFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("list", pflag.ContinueOnError)
+	addFanOutFlags(flags)
+	return flags
+}
+
+func flagStatusFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("status", pflag.ContinueOnError)
+	addFanOutFlags(flags)
+	return flags
 }
 
 func showFlags(c *ishell.Context) {
+	options := listFlagsFlagSet()
+	if !parseFlags(c, options) {
+		return
+	}
+	configs, err := fanOutConfigs(options)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	if configs != nil {
+		showFlagsForConfigs(c, configs)
+		return
+	}
+
 	configKey := currentConfig
 	projectKey := currentProject
 
@@ -389,30 +423,29 @@ func showFlags(c *ishell.Context) {
 		p := path.ResourcePath(c.Args[0])
 		switch {
 		case p.Depth() == 2:
-			_, flag := getFlagArg(c, 0)
+			flagPath, flag := getFlagArg(c, 0)
 			if flag == nil {
-				c.Println("Flag not found")
 				return
 			}
-			renderFlag(c, *flag)
+			renderFlag(c, *flag, showFlagBucketing(c, flagPath))
 			return
 		case p.Depth() == 1:
 			realPath, err := realProjPath(c.Args[0])
 			if err != nil {
-				c.Err(err)
+				reportError(c, err)
 				return
 			}
 			configKey = realPath.Config()
 			projectKey = realPath.Key()
 		default:
-			c.Err(errors.New("invalid path to project or flag"))
+			reportError(c, errors.New("invalid path to project or flag"))
 			return
 		}
 	}
 
 	flags, err := listFlags(configKey, projectKey)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	buf := bytes.Buffer{}
@@ -425,7 +458,132 @@ func showFlags(c *ishell.Context) {
 	renderPagedTable(c, buf)
 }
 
-func renderFlag(c *ishell.Context, flag ldapi.FeatureFlag) {
+// showFlagsForConfigs lists the flags of the given project, or the default project of each config
+func showFlagsForConfigs(c *ishell.Context, configs []string) {
+	if len(c.Args) > 1 {
+		reportError(c, errTooManyArgs)
+		return
+	}
+	renderForConfigs(c, configs, []string{"Project", "Key", "Name", "Description"}, func(configKey *string) (configRead, error) {
+		projectKey := firstOrEmpty(c.Args)
+		if projectKey == "" {
+			var err error
+			if projectKey, err = defaultProject(*configKey); err != nil {
+				return configRead{}, err
+			}
+		}
+		flags, err := listFlags(configKey, projectKey)
+		if err != nil {
+			return configRead{}, err
+		}
+		read := configRead{items: flags}
+		for _, flag := range flags {
+			read.rows = append(read.rows, []string{projectKey, flag.Key, flag.Name, flag.Description})
+		}
+		return read, nil
+	})
+}
+
+func showFlagStatuses(c *ishell.Context) {
+	options := flagStatusFlagSet()
+	if !parseFlags(c, options) {
+		return
+	}
+	configs, err := fanOutConfigs(options)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	if configs != nil {
+		showFlagStatusesForConfigs(c, configs)
+		return
+	}
+
+	if len(c.Args) > 0 {
+		flagPath, flag := getFlagConfigArg(c, 0)
+		if flag == nil {
+			return
+		}
+		auth := api.GetAuthCtx(getToken(flagPath.Config()))
+		client, err := api.GetClient(getServer(flagPath.Config()))
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		status, _, err := client.FeatureFlagsApi.GetFeatureFlagStatus(auth, flagPath.Project(), flagPath.Environment(), flagPath.Key())
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		c.Println("Status: " + status.Name)
+		c.Printf("Last Requested: %v\n", status.LastRequested)
+	} else {
+		auth := api.GetAuthCtx(getToken(currentConfig))
+		client, err := api.GetClient(getServer(currentConfig))
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		statuses, _, err := client.FeatureFlagsApi.GetFeatureFlagStatuses(auth, currentProject, currentEnvironment)
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		buf := bytes.Buffer{}
+		table := tablewriter.NewWriter(&buf)
+		table.SetHeader([]string{"Key", "Status", "Last Requested"})
+		for _, status := range statuses.Items {
+			table.Append([]string{flagKeyForStatus(status), status.Name, status.LastRequested})
+		}
+		table.Render()
+		c.Println(buf.String())
+	}
+}
+
+// showFlagStatusesForConfigs shows the statuses of the flags in the default project and environment of each config,
+// or of only the given flag
+func showFlagStatusesForConfigs(c *ishell.Context, configs []string) {
+	if len(c.Args) > 1 {
+		reportError(c, errTooManyArgs)
+		return
+	}
+	flagKey := firstOrEmpty(c.Args)
+	header := []string{"Project", "Environment", "Key", "Status", "Last Requested"}
+	renderForConfigs(c, configs, header, func(configKey *string) (configRead, error) {
+		projectKey, err := defaultProject(*configKey)
+		if err != nil {
+			return configRead{}, err
+		}
+		envKey := configFile[*configKey].DefaultEnvironment
+		if envKey == "" {
+			return configRead{}, fmt.Errorf("config %s has no default environment", *configKey)
+		}
+		client, err := api.GetClient(getServer(configKey))
+		if err != nil {
+			return configRead{}, err
+		}
+		auth := api.GetAuthCtx(getToken(configKey))
+		statuses, _, err := client.FeatureFlagsApi.GetFeatureFlagStatuses(auth, projectKey, envKey)
+		if err != nil {
+			return configRead{}, err
+		}
+		var read configRead
+		var items []ldapi.FeatureFlagStatus
+		for _, status := range statuses.Items {
+			key := flagKeyForStatus(status)
+			if flagKey != "" && key != flagKey {
+				continue
+			}
+			items = append(items, status)
+			read.rows = append(read.rows, []string{projectKey, envKey, key, status.Name, status.LastRequested})
+		}
+		read.items = items
+		return read, nil
+	})
+}
+
+// renderFlag shows a flag, including the bucketing attributes of its rollouts if they are known
+func renderFlag(c *ishell.Context, flag ldapi.FeatureFlag, bucketing *flagBucketing) {
 	if renderJSON(c) {
 		printJSON(c, flag)
 		return
@@ -461,18 +619,7 @@ func renderFlag(c *ishell.Context, flag ldapi.FeatureFlag) {
 	for envKey, envStatus := range flag.Environments {
 		row := []string{envKey, fmt.Sprintf("%v", envStatus.On), time.Unix(envStatus.LastModified/1000, 0).Format("2006/01/02 15:04")}
 		if envStatus.Fallthrough_ != nil && envStatus.Fallthrough_.Rollout != nil {
-			var rollout []string
-		NextVariation:
-			for i := range flag.Variations {
-				for variationNum, v := range envStatus.Fallthrough_.Rollout.Variations {
-					if variationNum == i {
-						rollout = append(rollout, fmt.Sprintf("%2.2f%%", float64(v.Weight)/1000.0))
-						continue NextVariation
-					}
-				}
-				rollout = append(rollout, "-")
-			}
-			row = append(row, strings.Join(rollout, "/"))
+			row = append(row, formatRollout(len(flag.Variations), envStatus.Fallthrough_.Rollout))
 		} else {
 			row = append(row, "")
 		}
@@ -480,6 +627,8 @@ func renderFlag(c *ishell.Context, flag ldapi.FeatureFlag) {
 	}
 	table.Render()
 	c.Println(buf.String())
+
+	renderFlagTargeting(c, flag, bucketing)
 }
 
 func createToggleFlag(c *ishell.Context) {
@@ -487,6 +636,10 @@ func createToggleFlag(c *ishell.Context) {
 	var p perProjectPath
 	switch len(c.Args) {
 	case 0:
+		if !canPrompt() {
+			reportError(c, errNoTerminal)
+			return
+		}
 		c.Print("Key: ")
 		key := c.ReadLine()
 		c.Print("Name: ")
@@ -496,10 +649,11 @@ func createToggleFlag(c *ishell.Context) {
 		var err error
 		p, err = realFlagPath(c.Args[0])
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
+			return
 		}
 		if p.Depth() != 2 {
-			c.Err(errors.New("invalid path"))
+			reportError(c, errors.New("invalid path"))
 			return
 		}
 		if len(c.Args) > 1 {
@@ -514,7 +668,7 @@ func createToggleFlag(c *ishell.Context) {
 	f = false
 	client, err := api.GetClient(getServer(p.Config()))
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 
@@ -525,40 +679,56 @@ func createToggleFlag(c *ishell.Context) {
 		Variations: []ldapi.Variation{{Value: &t}, {Value: &f}},
 	}, nil)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	if renderJSON(c) {
-		renderFlag(c, flag)
+		renderFlag(c, flag, nil)
 	}
 }
 
 func editFlag(c *ishell.Context) {
+	format, ok := parseEditFlags(c)
+	if !ok {
+		return
+	}
 	flagPath, flag := getFlagArg(c, 0)
 	if flag == nil {
 		return
 	}
-	data, _ := json.MarshalIndent(flag, "", "    ")
-	patchComment, err := editFile(c, data)
+	data, err := marshalEdit(flag)
 	if err != nil {
-		c.Err(err)
-		return
-	}
-
-	if patchComment == nil {
-		c.Println("No changes")
+		reportError(c, err)
 		return
 	}
 
-	client, err := api.GetClient(getServer(flagPath.Config()))
+	changed, err := editResource(c, editTarget{
+		kind:       "flag",
+		definition: "FeatureFlag",
+		newValue:   func() interface{} { return &ldapi.FeatureFlag{} },
+		fetch: func() ([]byte, int, error) {
+			current, err := getFlag(flagPath)
+			if err != nil {
+				return nil, 0, err
+			}
+			data, err := marshalEdit(current)
+			return data, int(current.Version), err
+		},
+		patch: func(patchComment ldapi.PatchComment) error {
+			_, resp, err := patchFlag(c, flagPath.Config(), flagPath.Project(), flag.Key, patchComment)
+			if resp != nil && resp.StatusCode == http.StatusConflict {
+				return errEditConflict
+			}
+			return err
+		},
+	}, format, data, int(flag.Version))
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
-	auth := api.GetAuthCtx(getToken(flagPath.Config()))
-	_, _, err = client.FeatureFlagsApi.PatchFeatureFlag(auth, flagPath.Project(), flag.Key, *patchComment)
-	if err != nil {
-		c.Err(err)
+
+	if !changed {
+		c.Println("No changes")
 		return
 	}
 
@@ -575,15 +745,9 @@ func addTag(c *ishell.Context) {
 		Value: interfacePtr(tag),
 	}}
 
-	client, err := api.GetClient(getServer(flagPath.Config()))
-	if err != nil {
-		c.Err(err)
-		return
-	}
-	auth := api.GetAuthCtx(getToken(flagPath.Config()))
-	_, _, err = client.FeatureFlagsApi.PatchFeatureFlag(auth, flagPath.Project(), flag.Key, patchComment)
+	_, _, err := patchFlag(c, flagPath.Config(), flagPath.Project(), flag.Key, patchComment)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 	}
 
 }
@@ -598,7 +762,8 @@ func removeTag(c *ishell.Context) {
 		}
 	}
 	if index < 0 {
-		c.Printf("Flag does not have tag %s", tag)
+		reportError(c, fmt.Errorf("flag does not have tag %s", tag))
+		return
 	}
 	var patchComment ldapi.PatchComment
 	patchComment.Patch = []ldapi.PatchOperation{{
@@ -606,16 +771,9 @@ func removeTag(c *ishell.Context) {
 		Path: fmt.Sprintf("/tags/%d", index),
 	}}
 
-	client, err := api.GetClient(getServer(flagPath.Config()))
-	if err != nil {
-		c.Err(err)
-		return
-	}
-	auth := api.GetAuthCtx(getToken(flagPath.Config()))
-
-	_, _, err = client.FeatureFlagsApi.PatchFeatureFlag(auth, flagPath.Project(), flagPath.Key(), patchComment)
+	_, _, err := patchFlag(c, flagPath.Config(), flagPath.Project(), flagPath.Key(), patchComment)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 	}
 
 }
@@ -632,24 +790,24 @@ func on(c *ishell.Context) {
 		Value: interfacePtr(true),
 	}}
 
-	client, err := api.GetClient(getServer(flagPath.Config()))
-	if err != nil {
-		c.Err(err)
-		return
-	}
-	auth := api.GetAuthCtx(getToken(flagPath.Config()))
-	_, _, err = client.FeatureFlagsApi.PatchFeatureFlag(auth, flagPath.Project(), flagPath.Key(), patchComment)
+	_, _, err := patchFlag(c, flagPath.Config(), flagPath.Project(), flagPath.Key(), patchComment)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 	}
 }
 
 func rollout(c *ishell.Context) {
+	flags := rolloutFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	ruleRef, _ := flags.GetString("rule")
+	bucketBy, _ := flags.GetString("bucket-by")
+
 	flagPath, flag := getFlagConfigArg(c, 0)
 	var patchComment ldapi.PatchComment
 
 	if flag == nil {
-		c.Err(errors.New("flag not found"))
 		return
 	}
 
@@ -663,21 +821,21 @@ func rollout(c *ishell.Context) {
 			if len(parts) > 1 {
 				index, err = strconv.Atoi(parts[0])
 				if err != nil {
-					c.Err(err)
+					reportError(c, err)
 					return
 				}
 			}
 			percent, err = strconv.ParseFloat(parts[len(parts)-1], 64)
 			if err != nil {
-				c.Err(err)
+				reportError(c, err)
 				return
 			}
 		} else {
 			for {
 				c.Printf("Enter rollout %% for variation %d (%v): ", i, *v.Value)
-				value, err := c.ReadLineErr()
+				value, err := readLine(c)
 				if err != nil {
-					c.Err(err)
+					reportError(c, err)
 					return
 				}
 				percent, err = strconv.ParseFloat(value, 64)
@@ -688,46 +846,60 @@ func rollout(c *ishell.Context) {
 				break
 			}
 		}
-		weight := int32(1000.0 * percent)
+		weight := int32(math.Round(1000.0 * percent))
 		variations = append(variations, ldapi.WeightedVariation{Variation: int32(index), Weight: weight})
 	}
+	if err := validateRollout(variations, len(flag.Variations)); err != nil {
+		reportError(c, err)
+		return
+	}
 
 	client, err := api.GetClient(getServer(flagPath.Config()))
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	auth := api.GetAuthCtx(getToken(flagPath.Config()))
 	originalFlag, _, err := client.FeatureFlagsApi.GetFeatureFlag(auth, flagPath.Project(), flagPath.Key(), nil)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 
-	var patches []ldapi.PatchOperation
-	originalFallthrough := originalFlag.Environments[flagPath.Environment()].Fallthrough_
-	if originalFallthrough.Rollout == nil {
-		patches = append(patches, ldapi.PatchOperation{
-			Op:   "remove",
-			Path: fmt.Sprintf("/environments/%s/fallthrough/variation", flagPath.Environment()),
-		})
+	originalConfig := originalFlag.Environments[flagPath.Environment()]
+	ruleIndex := -1
+	basePath := fmt.Sprintf("/environments/%s/fallthrough", flagPath.Environment())
+	hasRollout := originalConfig.Fallthrough_ != nil && originalConfig.Fallthrough_.Rollout != nil
+	if ruleRef != "" {
+		ruleIndex, err = findRule(originalConfig.Rules, ruleRef)
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		basePath = fmt.Sprintf("/environments/%s/rules/%d", flagPath.Environment(), ruleIndex)
+		hasRollout = originalConfig.Rules[ruleIndex].Rollout != nil
 	}
 
-	patches = append(patches, ldapi.PatchOperation{
-		Op:    "replace",
-		Path:  fmt.Sprintf("/environments/%s/fallthrough/rollout", flagPath.Environment()),
-		Value: interfacePtr(ldapi.Rollout{Variations: variations}),
-	})
+	patchComment.Patch = rolloutPatches(basePath, hasRollout, variations, bucketBy)
 
-	patchComment.Patch = patches
-
-	patchedFlag, _, err := client.FeatureFlagsApi.PatchFeatureFlag(auth, flagPath.Project(), flagPath.Key(), patchComment)
+	patchedFlag, _, err := patchFlag(c, flagPath.Config(), flagPath.Project(), flagPath.Key(), patchComment)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 
-	final := patchedFlag.Environments[flagPath.Environment()].Fallthrough_.Rollout
+	patchedConfig := patchedFlag.Environments[flagPath.Environment()]
+	var final *ldapi.Rollout
+	if ruleIndex < 0 && patchedConfig.Fallthrough_ != nil {
+		final = patchedConfig.Fallthrough_.Rollout
+	}
+	if ruleIndex >= 0 && ruleIndex < len(patchedConfig.Rules) {
+		final = patchedConfig.Rules[ruleIndex].Rollout
+	}
+	if final == nil {
+		reportError(c, errors.New("the rollout was not applied"))
+		return
+	}
 
 	if renderJSON(c) {
 		printJSON(c, final)
@@ -738,7 +910,7 @@ func rollout(c *ishell.Context) {
 	table := tablewriter.NewWriter(&buf)
 	table.SetHeader([]string{"Index", "Weight"})
 	for _, v := range final.Variations {
-		table.Append([]string{strconv.Itoa(int(v.Variation)), fmt.Sprintf("%2.2f%%", float64(v.Weight)/1000.0)})
+		table.Append([]string{strconv.Itoa(int(v.Variation)), formatWeight(v.Weight)})
 	}
 	table.Render()
 	c.Print(buf.String())
@@ -749,13 +921,7 @@ func rolloutCompleter(args []string) (completions []string) {
 		return nonFinalCompleter(flagCompleter)(args)
 	}
 
-	client, err := api.GetClient(getServer(currentConfig))
-	if err != nil {
-		return nil
-	}
-	auth := api.GetAuthCtx(getToken(currentConfig))
-
-	currentFlag, _, err := client.FeatureFlagsApi.GetFeatureFlag(auth, currentProject, args[0], nil)
+	currentFlag, err := getCachedFlag(currentConfig, currentProject, args[0])
 	if err != nil {
 		return nil
 	}
@@ -781,20 +947,19 @@ func fallthru(c *ishell.Context) {
 	var patchComment ldapi.PatchComment
 
 	if flag == nil {
-		c.Err(errors.New("flag not found"))
 		return
 	}
 
 	client, err := api.GetClient(getServer(flagPath.Config()))
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	auth := api.GetAuthCtx(getToken(flagPath.Config()))
 
 	originalFlag, _, err := client.FeatureFlagsApi.GetFeatureFlag(auth, flagPath.Project(), flag.Key, nil)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 
@@ -803,7 +968,8 @@ func fallthru(c *ishell.Context) {
 		parts := strings.SplitN(c.Args[1], ":", 2)
 		value, err = strconv.Atoi(parts[0])
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
+			return
 		}
 	} else {
 		var options []string
@@ -812,16 +978,16 @@ func fallthru(c *ishell.Context) {
 			if name == "" {
 				data, err := json.Marshal(v.Value)
 				if err != nil {
-					c.Err(err)
+					reportError(c, err)
 					return
 				}
 				name = string(data)
 			}
 			options = append(options, name)
 		}
-		value = c.MultiChoice(options, "Choose a fallthrough variation: ")
-		if value < 0 {
-			c.Err(errors.New("unknown choice"))
+		var err error
+		if value, err = multiChoice(c, options, "Choose a fallthrough variation: "); err != nil {
+			reportError(c, err)
 			return
 		}
 	}
@@ -843,9 +1009,9 @@ func fallthru(c *ishell.Context) {
 
 	patchComment.Patch = patches
 
-	patchedFlag, _, err := client.FeatureFlagsApi.PatchFeatureFlag(auth, currentProject, flag.Key, patchComment)
+	patchedFlag, _, err := patchFlag(c, flagPath.Config(), flagPath.Project(), flag.Key, patchComment)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 
@@ -862,13 +1028,7 @@ func fallthruCompleter(args []string) (completions []string) {
 		return nil
 	}
 
-	client, err := api.GetClient(getServer(currentConfig))
-	if err != nil {
-		return nil
-	}
-	auth := api.GetAuthCtx(getToken(currentConfig))
-
-	currentFlag, _, err := client.FeatureFlagsApi.GetFeatureFlag(auth, currentProject, args[0], nil)
+	currentFlag, err := getCachedFlag(currentConfig, currentProject, args[0])
 	if err != nil {
 		return nil
 	}
@@ -896,15 +1056,9 @@ func off(c *ishell.Context) {
 		Path:  fmt.Sprintf("/environments/%s/on", flagPath.Environment()),
 		Value: interfacePtr(false),
 	}}
-	client, err := api.GetClient(getServer(flagPath.Config()))
-	if err != nil {
-		c.Err(err)
-		return
-	}
-	auth := api.GetAuthCtx(getToken(flagPath.Config()))
-	_, _, err = client.FeatureFlagsApi.PatchFeatureFlag(auth, flagPath.Project(), flag.Key, patchComment)
+	_, _, err := patchFlag(c, flagPath.Config(), flagPath.Project(), flag.Key, patchComment)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 	}
 }
 
@@ -918,7 +1072,6 @@ func createFlag(c *ishell.Context) {
 func deleteFlag(c *ishell.Context) {
 	flagPath, flag := getFlagArg(c, 0)
 	if flag == nil {
-		c.Err(errNotFound)
 		return
 	}
 
@@ -927,13 +1080,13 @@ func deleteFlag(c *ishell.Context) {
 	}
 	client, err := api.GetClient(getServer(flagPath.Config()))
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	auth := api.GetAuthCtx(getToken(flagPath.Config()))
 	_, err = client.FeatureFlagsApi.DeleteFeatureFlag(auth, flagPath.Project(), flag.Key)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 
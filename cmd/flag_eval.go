@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/pflag"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+
+	"github.com/launchdarkly/ldc/api"
+	"github.com/launchdarkly/ldc/cmd/internal/eval"
+	"github.com/launchdarkly/ldc/cmd/internal/path"
+)
+
+// apiEvalSource fetches the prerequisites and segments of a flag from the api as they are needed
+type apiEvalSource struct {
+	envPath   perProjectPath
+	flags     map[string]*ldapi.FeatureFlag
+	segments  map[string]*ldapi.UserSegment
+	bucketing map[string]*flagBucketing
+}
+
+func newAPIEvalSource(envPath perProjectPath) *apiEvalSource {
+	return &apiEvalSource{
+		envPath:   envPath,
+		flags:     make(map[string]*ldapi.FeatureFlag),
+		segments:  make(map[string]*ldapi.UserSegment),
+		bucketing: make(map[string]*flagBucketing),
+	}
+}
+
+func (s *apiEvalSource) GetFlag(key string) (*ldapi.FeatureFlag, error) {
+	if flag, ok := s.flags[key]; ok {
+		return flag, nil
+	}
+	flag, err := getFlag(perProjectPath{path.NewAbsPath(s.envPath.Config(), s.envPath.Project(), key)})
+	if err != nil {
+		return nil, fmt.Errorf(`unable to get flag "%s": %w`, key, err)
+	}
+	s.flags[key] = flag
+	return flag, nil
+}
+
+func (s *apiEvalSource) GetSegment(key string) (*ldapi.UserSegment, error) {
+	if segment, ok := s.segments[key]; ok {
+		return segment, nil
+	}
+	client, err := api.GetClient(getServer(s.envPath.Config()))
+	if err != nil {
+		return nil, err
+	}
+	auth := api.GetAuthCtx(getToken(s.envPath.Config()))
+	segment, _, err := client.UserSegmentsApi.GetUserSegment(auth, s.envPath.Project(), s.envPath.Key(), key)
+	if err != nil {
+		return nil, fmt.Errorf(`unable to get segment "%s": %w`, key, err)
+	}
+	s.segments[key] = &segment
+	return &segment, nil
+}
+
+// BucketBy reads the attribute a rollout buckets users by from the flag json, as the api client does not include it
+func (s *apiEvalSource) BucketBy(flagKey string, rule int) (string, error) {
+	bucketing, err := s.getBucketing(flagKey)
+	if err != nil {
+		return "", err
+	}
+	return bucketing.bucketBy(s.envPath.Key(), rule), nil
+}
+
+// OffVariation reads the off variation from the flag json, as the api client turns a missing one into variation 0
+func (s *apiEvalSource) OffVariation(flagKey string) (int, bool, error) {
+	bucketing, err := s.getBucketing(flagKey)
+	if err != nil {
+		return 0, false, err
+	}
+	offVariation := bucketing.Environments[s.envPath.Key()].OffVariation
+	if offVariation == nil {
+		return 0, false, nil
+	}
+	return int(*offVariation), true, nil
+}
+
+func (s *apiEvalSource) getBucketing(flagKey string) (*flagBucketing, error) {
+	if bucketing, ok := s.bucketing[flagKey]; ok {
+		return bucketing, nil
+	}
+	bucketing, err := getFlagBucketing(perProjectPath{path.NewAbsPath(s.envPath.Config(), s.envPath.Project(), flagKey)})
+	if err != nil {
+		return nil, fmt.Errorf(`unable to get flag "%s": %w`, flagKey, err)
+	}
+	s.bucketing[flagKey] = bucketing
+	return bucketing, nil
+}
+
+func evalFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("eval", pflag.ContinueOnError)
+	flags.String("user", "", `user json, e.g. {"key":"user-key","email":"user@example.com"}`)
+	return flags
+}
+
+func evalFlag(c *ishell.Context) {
+	flags := evalFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	userJSON, _ := flags.GetString("user")
+	if userJSON == "" {
+		if len(c.Args) < 2 {
+			reportError(c, errors.New(`a user is required: eval <flag> --user '{"key":"user-key"}'`))
+			return
+		}
+		userJSON = c.Args[1]
+	}
+
+	var user eval.User
+	if err := json.Unmarshal([]byte(userJSON), &user); err != nil {
+		reportError(c, fmt.Errorf("invalid user json: %s", err))
+		return
+	}
+	if _, ok := user["key"]; !ok {
+		reportError(c, errors.New(`user must have a "key"`))
+		return
+	}
+
+	flagPath, flag := getFlagConfigArg(c, 0)
+	if flag == nil {
+		return
+	}
+
+	source := newAPIEvalSource(perProjectPath{path.NewAbsPath(flagPath.Config(), flagPath.Project(), flagPath.Environment())})
+	source.flags[flag.Key] = flag
+	result, err := eval.NewEvaluator(flagPath.Environment(), source).Evaluate(*flag, user)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+
+	if renderJSON(c) {
+		printJSON(c, result)
+		return
+	}
+
+	buf := bytes.Buffer{}
+	table := tablewriter.NewWriter(&buf)
+	table.SetHeader([]string{"Field", "Value"})
+	if result.VariationIndex != nil {
+		variation := flag.Variations[*result.VariationIndex]
+		value, _ := json.Marshal(result.Value)
+		table.Append([]string{"Variation", strconv.Itoa(*result.VariationIndex)})
+		table.Append([]string{"Name", variation.Name})
+		table.Append([]string{"Value", string(value)})
+	} else {
+		table.Append([]string{"Variation", "<none>"})
+	}
+	table.Append([]string{"Reason", result.Reason.String()})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.Render()
+	c.Print(buf.String())
+}
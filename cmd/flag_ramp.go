@@ -0,0 +1,696 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/pflag"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+
+	"github.com/launchdarkly/ldc/api"
+	"github.com/launchdarkly/ldc/cmd/internal/ramp"
+)
+
+const defaultRampInterval = 30 * time.Minute
+
+// rampPollInterval is how often a waiting ramp checks whether it has been aborted
+const rampPollInterval = 5 * time.Second
+
+var errRampConflict = errors.New("the flag was changed by someone else")
+
+func addRampCommands(root *ishell.Cmd) {
+	rampCmd := withFlags(&ishell.Cmd{
+		Name:      "ramp",
+		Help:      "progressively roll out a variation: ramp <flag> --to <variation> --steps 1,5,25,50,100 [--interval 30m] [--on-conflict pause|rollback] [--daemon]",
+		Completer: flagEnvCompleter,
+		Func:      rampFlag,
+	}, rampFlagSet)
+	rampCmd.AddCmd(&ishell.Cmd{
+		Name:      "status",
+		Help:      "show the progress of ramps: ramp status [flag]",
+		Completer: flagEnvCompleter,
+		Func:      showRampStatus,
+	})
+	rampCmd.AddCmd(withFlags(&ishell.Cmd{
+		Name:      "abort",
+		Help:      "stop a ramp: ramp abort <flag> [--rollback]",
+		Completer: flagEnvCompleter,
+		Func:      abortRamp,
+	}, rampAbortFlagSet))
+	root.AddCmd(rampCmd)
+}
+
+func rampFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("ramp", pflag.ContinueOnError)
+	flags.String("to", "", "index or name of the variation to roll out")
+	flags.String("steps", "", "comma-separated percentages of users to roll out to, e.g. 1,5,25,50,100")
+	flags.Duration("interval", defaultRampInterval, "time between steps")
+	flags.String("on-conflict", ramp.OnConflictPause, "what to do when someone else changes the flag: pause or rollback")
+	flags.Bool("daemon", false, "run the ramp in the background")
+	return flags
+}
+
+func rampAbortFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("abort", pflag.ContinueOnError)
+	flags.Bool("rollback", false, "restore the fallthrough from before the ramp")
+	return flags
+}
+
+func rampDir() (string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "ldc", "ramps"), nil
+}
+
+// rampStateFile nests the state of a ramp in a directory for each of the config, project and environment, so that
+// keys containing separators cannot make two ramps share a file
+func rampStateFile(flagPath perEnvironmentPath) (string, error) {
+	dir, err := rampDir()
+	if err != nil {
+		return "", err
+	}
+	config := "default"
+	if flagPath.Config() != nil {
+		config = *flagPath.Config()
+	}
+	var elems []string
+	for _, name := range []string{config, flagPath.Project(), flagPath.Environment(), flagPath.Key()} {
+		elems = append(elems, url.PathEscape(name))
+	}
+	return filepath.Join(dir, filepath.Join(elems...)+".json"), nil
+}
+
+func rampFlag(c *ishell.Context) {
+	flags := rampFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	to, _ := flags.GetString("to")
+	rawSteps, _ := flags.GetString("steps")
+	interval, _ := flags.GetDuration("interval")
+	onConflict, _ := flags.GetString("on-conflict")
+	daemon, _ := flags.GetBool("daemon")
+	if onConflict != ramp.OnConflictPause && onConflict != ramp.OnConflictRollback {
+		reportError(c, fmt.Errorf(`invalid --on-conflict "%s": must be pause or rollback`, onConflict))
+		return
+	}
+	if interval <= 0 {
+		reportError(c, errors.New("--interval must be positive"))
+		return
+	}
+
+	flagPath, flag := getFlagConfigArg(c, 0)
+	if flag == nil {
+		return
+	}
+	config, ok := flag.Environments[flagPath.Environment()]
+	if !ok {
+		reportError(c, fmt.Errorf(`flag is not configured for environment "%s"`, flagPath.Environment()))
+		return
+	}
+	stateFile, err := rampStateFile(flagPath)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	state, err := ramp.Load(stateFile)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+
+	if pid := otherRampRunner(state); pid != 0 {
+		reportError(c, fmt.Errorf("a ramp of this flag is already running in process %d; use 'flags ramp abort' to stop it first", pid))
+		return
+	}
+
+	if state != nil && state.Active() {
+		if flags.Changed("to") || flags.Changed("steps") {
+			reportError(c, fmt.Errorf("a ramp of this flag is already %s; use 'flags ramp abort' to stop it first", state.Status))
+			return
+		}
+		if state.Status == ramp.StatusPaused {
+			// resuming accepts any changes made while the ramp was paused
+			c.Printf("Resuming ramp paused at step %d of %d: %s\n", state.Step, len(state.Steps), state.Reason)
+			state.Status = ramp.StatusRunning
+			state.Reason = ""
+			state.Version = flag.Version
+		} else if state.PID != 0 && state.PID != os.Getpid() {
+			c.Printf("Taking over the ramp from process %d, which is no longer running\n", state.PID)
+		}
+	} else {
+		if to == "" || rawSteps == "" {
+			reportError(c, errors.New("--to and --steps are required to start a ramp"))
+			return
+		}
+		toIndex, err := variationIndex(flag, to)
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		steps, err := ramp.ParseSteps(rawSteps)
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		original := ldapi.ModelFallthrough{}
+		if config.Fallthrough_ != nil {
+			original = *config.Fallthrough_
+		}
+		if _, err := ramp.Weights(original, len(flag.Variations), toIndex, steps[0]); err != nil {
+			reportError(c, err)
+			return
+		}
+		if limit := configFile[getConfigName(flagPath.Config())].MaxRolloutStep; limit > 0 {
+			if step := ramp.LargestStep(original, toIndex, steps); step > limit {
+				reportError(c, fmt.Errorf(`the ramp has a step of %v%% of users, more than the maxRolloutStep of %v%% for config "%s"`,
+					step, limit, getConfigName(flagPath.Config())))
+				return
+			}
+		}
+		state = &ramp.State{
+			Path:       flagPath.String(),
+			To:         toIndex,
+			Steps:      steps,
+			Interval:   interval.String(),
+			OnConflict: onConflict,
+			Version:    flag.Version,
+			Original:   original,
+			NextStepAt: time.Now(),
+			Status:     ramp.StatusRunning,
+		}
+	}
+
+	// the whole ramp is commented and confirmed once rather than at each step
+	envs := []string{flagPath.Environment()}
+	if state.Comment == "" {
+		comment, err := getChangeComment(c, flagPath.Config(), envs, "")
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		state.Comment = comment
+	}
+	if err := confirmChange(c, flagPath.Config(), envs); err != nil {
+		reportError(c, err)
+		return
+	}
+	if api.DryRun {
+		// show the next step without saving the ramp or waiting for it
+		_, err := applyRampStep(flagPath, state, state.Steps[state.Step])
+		reportError(c, err)
+		return
+	}
+	// a daemon claims the ramp itself when it starts
+	pid := os.Getpid()
+	if daemon {
+		pid = 0
+	}
+	if err := claimRamp(stateFile, state, pid); err != nil {
+		reportError(c, err)
+		return
+	}
+
+	if daemon {
+		pid, logFile, err := startRampDaemon(flagPath, stateFile)
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		c.Printf("Started ramp in the background (pid %d), logging to %s\n", pid, logFile)
+		return
+	}
+	if err := runRamp(c, flagPath, stateFile, state); err != nil {
+		reportError(c, err)
+	}
+}
+
+// otherRampRunner returns the id of another process that is running a ramp, or 0 if there is none.  A ramp whose
+// process has died can be taken over.
+func otherRampRunner(state *ramp.State) int {
+	if state == nil || state.Status != ramp.StatusRunning || state.PID == 0 || state.PID == os.Getpid() ||
+		!processRunning(state.PID) {
+		return 0
+	}
+	return state.PID
+}
+
+// claimRamp saves a ramp as being run by the given process, unless another process started running it since it was
+// loaded
+func claimRamp(stateFile string, state *ramp.State, pid int) error {
+	unlock, err := ramp.Lock(stateFile)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	latest, err := ramp.Load(stateFile)
+	if err != nil {
+		return err
+	}
+	if other := otherRampRunner(latest); other != 0 {
+		return fmt.Errorf("a ramp of this flag was started in process %d in the meantime", other)
+	}
+	state.PID = pid
+	return state.Save(stateFile)
+}
+
+// ownsRamp returns true if the latest saved state shows the ramp is still running in the process that loaded state
+func ownsRamp(latest *ramp.State, state *ramp.State) bool {
+	return latest != nil && latest.Status == ramp.StatusRunning && latest.PID == state.PID
+}
+
+// runRamp applies the remaining steps of a ramp, saving its progress after each one
+func runRamp(c *ishell.Context, flagPath perEnvironmentPath, stateFile string, state *ramp.State) error {
+	interval, err := time.ParseDuration(state.Interval)
+	if err != nil {
+		return err
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	for state.Step < len(state.Steps) {
+		if wait := time.Until(state.NextStepAt); wait > 0 {
+			c.Printf("%s Waiting until %s for step %d of %d\n", rampTimestamp(), state.NextStepAt.Format("15:04:05"),
+				state.Step+1, len(state.Steps))
+		}
+		for time.Now().Before(state.NextStepAt) {
+			wait := time.Until(state.NextStepAt)
+			if wait > rampPollInterval {
+				wait = rampPollInterval
+			}
+			select {
+			case <-interrupt:
+				return pauseRamp(c, stateFile, state)
+			case <-time.After(wait):
+			}
+			latest, err := ramp.Load(stateFile)
+			if err != nil {
+				return err
+			}
+			if !ownsRamp(latest, state) {
+				c.Printf("%s Ramp stopped: %s\n", rampTimestamp(), rampStatusDescription(latest))
+				return nil
+			}
+		}
+
+		if stopped, err := runRampStep(c, flagPath, stateFile, state, interval); stopped || err != nil {
+			return err
+		}
+	}
+	c.Printf("%s Ramp complete\n", rampTimestamp())
+	return nil
+}
+
+// runRampStep applies the next step of a ramp.  The state file is locked while the step is applied and saved, so
+// that an abort is either seen before the step or waits until it is saved.  It returns true if the ramp stopped.
+func runRampStep(c *ishell.Context, flagPath perEnvironmentPath, stateFile string, state *ramp.State,
+	interval time.Duration) (bool, error) {
+	unlock, err := ramp.Lock(stateFile)
+	if err != nil {
+		return true, err
+	}
+	defer unlock()
+	latest, err := ramp.Load(stateFile)
+	if err != nil {
+		return true, err
+	}
+	if !ownsRamp(latest, state) {
+		c.Printf("%s Ramp stopped: %s\n", rampTimestamp(), rampStatusDescription(latest))
+		return true, nil
+	}
+
+	percent := state.Steps[state.Step]
+	version, err := applyRampStep(flagPath, state, percent)
+	if err == errRampConflict {
+		return true, handleRampConflict(c, flagPath, stateFile, state)
+	}
+	if err != nil {
+		// leave the ramp running so it resumes from this step when restarted
+		_ = state.Save(stateFile)
+		return true, err
+	}
+	state.Step++
+	state.Version = version
+	state.NextStepAt = time.Now().Add(interval)
+	if state.Step == len(state.Steps) {
+		state.Status = ramp.StatusCompleted
+	}
+	if err := state.Save(stateFile); err != nil {
+		return true, err
+	}
+	c.Printf("%s Step %d of %d: %v%% of users receive variation %d\n", rampTimestamp(), state.Step, len(state.Steps),
+		percent, state.To)
+	return false, nil
+}
+
+// pauseRamp saves an interrupted ramp as paused, unless it was stopped in the meantime
+func pauseRamp(c *ishell.Context, stateFile string, state *ramp.State) error {
+	unlock, err := ramp.Lock(stateFile)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	latest, err := ramp.Load(stateFile)
+	if err != nil {
+		return err
+	}
+	if !ownsRamp(latest, state) {
+		c.Printf("%s Ramp stopped: %s\n", rampTimestamp(), rampStatusDescription(latest))
+		return nil
+	}
+	state.Status = ramp.StatusPaused
+	state.Reason = "interrupted"
+	c.Printf("%s Paused; run the same command again to resume\n", rampTimestamp())
+	return state.Save(stateFile)
+}
+
+// applyRampStep updates the fallthrough rollout, failing with errRampConflict if the flag has changed since the
+// last step
+func applyRampStep(flagPath perEnvironmentPath, state *ramp.State, percent float64) (int32, error) {
+	client, err := api.GetClient(getServer(flagPath.Config()))
+	if err != nil {
+		return 0, err
+	}
+	auth := api.GetAuthCtx(getToken(flagPath.Config()))
+	flag, _, err := client.FeatureFlagsApi.GetFeatureFlag(auth, flagPath.Project(), flagPath.Key(), nil)
+	if err != nil {
+		return 0, err
+	}
+	if flag.Version != state.Version {
+		return 0, errRampConflict
+	}
+
+	variations, err := ramp.Weights(state.Original, len(flag.Variations), state.To, percent)
+	if err != nil {
+		return 0, err
+	}
+	if err := validateRollout(variations, len(flag.Variations)); err != nil {
+		return 0, err
+	}
+	fallthru := flag.Environments[flagPath.Environment()].Fallthrough_
+	patches := []ldapi.PatchOperation{{
+		Op:    "test",
+		Path:  "/_version",
+		Value: interfacePtr(state.Version),
+	}}
+	patches = append(patches, rolloutPatches(fmt.Sprintf("/environments/%s/fallthrough", flagPath.Environment()),
+		fallthru != nil && fallthru.Rollout != nil, variations, "")...)
+	patchComment := ldapi.PatchComment{
+		Comment: rampComment(state, fmt.Sprintf("ramp step %d of %d: %v%% to variation %d", state.Step+1, len(state.Steps), percent, state.To)),
+		Patch:   patches,
+	}
+	if err := checkRolloutSteps(flagPath.Config(), flagPath.Project(), flagPath.Key(), patches); err != nil {
+		return 0, err
+	}
+
+	patchedFlag, resp, err := sendFlagPatch(flagPath.Config(), flagPath.Project(), flagPath.Key(), patchComment)
+	if resp != nil && resp.StatusCode == http.StatusConflict {
+		return 0, errRampConflict
+	}
+	if err != nil {
+		return 0, err
+	}
+	return patchedFlag.Version, nil
+}
+
+func handleRampConflict(c *ishell.Context, flagPath perEnvironmentPath, stateFile string, state *ramp.State) error {
+	state.Reason = errRampConflict.Error()
+	if state.OnConflict == ramp.OnConflictRollback {
+		if err := rollbackRamp(flagPath, state); err != nil {
+			state.Status = ramp.StatusPaused
+			state.Reason = fmt.Sprintf("%s and the rollback failed: %s", errRampConflict, err)
+			_ = state.Save(stateFile)
+			return errors.New("ramp paused: " + state.Reason)
+		}
+		state.Status = ramp.StatusRolledBack
+		if err := state.Save(stateFile); err != nil {
+			return err
+		}
+		return errors.New("ramp rolled back: " + state.Reason)
+	}
+	state.Status = ramp.StatusPaused
+	if err := state.Save(stateFile); err != nil {
+		return err
+	}
+	return errors.New("ramp paused: " + state.Reason + "; run the same command again to resume")
+}
+
+// rollbackRamp restores the fallthrough from before the ramp started
+func rollbackRamp(flagPath perEnvironmentPath, state *ramp.State) error {
+	client, err := api.GetClient(getServer(flagPath.Config()))
+	if err != nil {
+		return err
+	}
+	auth := api.GetAuthCtx(getToken(flagPath.Config()))
+	flag, _, err := client.FeatureFlagsApi.GetFeatureFlag(auth, flagPath.Project(), flagPath.Key(), nil)
+	if err != nil {
+		return err
+	}
+
+	fallthroughPath := fmt.Sprintf("/environments/%s/fallthrough", flagPath.Environment())
+	current := flag.Environments[flagPath.Environment()].Fallthrough_
+	hasRollout := current != nil && current.Rollout != nil
+	var patches []ldapi.PatchOperation
+	if state.Original.Rollout != nil {
+		patches = rolloutPatches(fallthroughPath, hasRollout, state.Original.Rollout.Variations, "")
+	} else {
+		if hasRollout {
+			patches = append(patches, ldapi.PatchOperation{Op: "remove", Path: fallthroughPath + "/rollout"})
+		}
+		patches = append(patches, ldapi.PatchOperation{Op: "replace", Path: fallthroughPath + "/variation",
+			Value: interfacePtr(state.Original.Variation)})
+	}
+	patchComment := ldapi.PatchComment{Comment: rampComment(state, "ramp rolled back"), Patch: patches}
+	_, _, err = sendFlagPatch(flagPath.Config(), flagPath.Project(), flagPath.Key(), patchComment)
+	return err
+}
+
+// rampComment describes a change made by a ramp, followed by the comment the ramp was started with
+func rampComment(state *ramp.State, change string) string {
+	comment := "ldc " + change
+	if state.Comment != "" {
+		comment += ": " + state.Comment
+	}
+	return comment
+}
+
+// startRampDaemon runs the ramp in a background process that picks up the saved state
+func startRampDaemon(flagPath perEnvironmentPath, stateFile string) (int, string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, "", err
+	}
+	var args []string
+	if configFile := configViper.ConfigFileUsed(); configFile != "" {
+		args = append(args, "--config-file", configFile)
+	}
+	// the ramp was confirmed before it was started
+	args = append(args, "--yes", "flags", "ramp", flagPath.String())
+
+	logFile := strings.TrimSuffix(stateFile, filepath.Ext(stateFile)) + ".log"
+	log, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return 0, "", err
+	}
+	defer log.Close() // nolint:errcheck // the child has its own handle
+
+	cmd := exec.Command(executable, args...) // nolint:gosec // ok to launch ourselves
+	cmd.Stdout = log
+	cmd.Stderr = log
+	cmd.Env = os.Environ()
+	// pass credentials that did not come from the config file through the environment rather than the command line
+	if currentToken != "" {
+		cmd.Env = append(cmd.Env, "LDC_TOKEN="+currentToken)
+	}
+	if currentServer != "" {
+		cmd.Env = append(cmd.Env, "LDC_SERVER="+currentServer)
+	}
+	cmd.SysProcAttr = daemonSysProcAttr()
+	if err := cmd.Start(); err != nil {
+		return 0, "", err
+	}
+	pid := cmd.Process.Pid
+	return pid, logFile, cmd.Process.Release()
+}
+
+func showRampStatus(c *ishell.Context) {
+	var states []*ramp.State
+	if len(c.Args) > 0 {
+		flagPath, err := realFlagConfigPath(c.Args[0])
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		stateFile, err := rampStateFile(flagPath)
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		state, err := ramp.Load(stateFile)
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		if state == nil {
+			reportError(c, errors.New("no ramp found for flag"))
+			return
+		}
+		states = append(states, state)
+	} else {
+		dir, err := rampDir()
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		err = filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || filepath.Ext(name) != ".json" {
+				return err
+			}
+			state, err := ramp.Load(name)
+			if err != nil {
+				return err
+			}
+			states = append(states, state)
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			reportError(c, err)
+			return
+		}
+	}
+
+	if renderJSON(c) {
+		printJSON(c, states)
+		return
+	}
+
+	buf := bytes.Buffer{}
+	table := tablewriter.NewWriter(&buf)
+	table.SetHeader([]string{"Flag", "Status", "Step", "Percent", "Next Step", "PID", "Reason"})
+	for _, s := range states {
+		next := ""
+		if s.Status == ramp.StatusRunning {
+			next = s.NextStepAt.Format(time.RFC3339)
+		}
+		pid := ""
+		if s.PID != 0 && s.Status == ramp.StatusRunning {
+			pid = strconv.Itoa(s.PID)
+			if !processRunning(s.PID) {
+				pid += " (not running)"
+			}
+		}
+		table.Append([]string{s.Path, s.Status, fmt.Sprintf("%d/%d", s.Step, len(s.Steps)),
+			fmt.Sprintf("%v%%", s.Percent()), next, pid, s.Reason})
+	}
+	table.Render()
+	c.Print(buf.String())
+}
+
+func abortRamp(c *ishell.Context) {
+	flags := rampAbortFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	rollback, _ := flags.GetBool("rollback")
+	if len(c.Args) != 1 {
+		reportError(c, errors.New("a flag is required: ramp abort <flag>"))
+		return
+	}
+	flagPath, err := realFlagConfigPath(c.Args[0])
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	stateFile, err := rampStateFile(flagPath)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	// hold the lock until the abort is saved so a running ramp cannot apply another step in between
+	unlock, err := ramp.Lock(stateFile)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	defer unlock()
+	state, err := ramp.Load(stateFile)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	if state == nil || !state.Active() {
+		reportError(c, errors.New("no active ramp found for flag"))
+		return
+	}
+
+	state.Status = ramp.StatusAborted
+	state.Reason = "aborted"
+	if rollback {
+		if err := confirmChange(c, flagPath.Config(), []string{flagPath.Environment()}); err != nil {
+			reportError(c, err)
+			return
+		}
+		if err := rollbackRamp(flagPath, state); err != nil {
+			reportError(c, err)
+			return
+		}
+		state.Status = ramp.StatusRolledBack
+	}
+	if api.DryRun {
+		c.Printf("WRITE %s\n", stateFile)
+		reportError(c, api.ErrDryRun)
+		return
+	}
+	if err := state.Save(stateFile); err != nil {
+		reportError(c, err)
+		return
+	}
+	c.Printf("Ramp %s at step %d of %d\n", state.Status, state.Step, len(state.Steps))
+}
+
+// variationIndex finds a variation by index, name or json value
+func variationIndex(flag *ldapi.FeatureFlag, s string) (int, error) {
+	if i, err := strconv.Atoi(s); err == nil {
+		if i < 0 || i >= len(flag.Variations) {
+			return 0, fmt.Errorf("variation %d does not exist", i)
+		}
+		return i, nil
+	}
+	for i, v := range flag.Variations {
+		if v.Name == s || (v.Value != nil && fmt.Sprint(*v.Value) == s) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf(`variation "%s" does not exist`, s)
+}
+
+func rampStatusDescription(state *ramp.State) string {
+	if state == nil {
+		return "state file was removed"
+	}
+	if state.Reason != "" {
+		return state.Status + " (" + state.Reason + ")"
+	}
+	return state.Status
+}
+
+func rampTimestamp() string {
+	return time.Now().Format("2006-01-02 15:04:05")
+}
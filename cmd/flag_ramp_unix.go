@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import "syscall"
+
+// daemonSysProcAttr detaches background ramps from the terminal so they survive the shell exiting
+func daemonSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// processRunning returns true if a process with the given id exists
+func processRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,24 @@
+//go:build windows
+// +build windows
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// daemonSysProcAttr uses the defaults on windows, which has no sessions to detach from
+func daemonSysProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+// processRunning returns true if a process with the given id exists
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
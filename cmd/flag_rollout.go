@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/pflag"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+
+	"github.com/launchdarkly/ldc/api"
+)
+
+// rolloutTotalWeight is the sum of the weights of a rollout, which are in thousandths of a percent
+const rolloutTotalWeight = 100000
+
+// bucketedRollout is an ldapi.Rollout with the attribute used to bucket users, which the api client does not support
+type bucketedRollout struct {
+	Variations []weightedVariation `json:"variations"`
+	BucketBy   string              `json:"bucketBy,omitempty"`
+}
+
+// weightedVariation is an ldapi.WeightedVariation that does not omit variation 0
+type weightedVariation struct {
+	Variation int32 `json:"variation"`
+	Weight    int32 `json:"weight"`
+}
+
+func toWeightedVariations(variations []ldapi.WeightedVariation) []weightedVariation {
+	result := make([]weightedVariation, 0, len(variations))
+	for _, wv := range variations {
+		result = append(result, weightedVariation{Variation: wv.Variation, Weight: wv.Weight})
+	}
+	return result
+}
+
+// flagBucketing holds the bucketing attributes of the rollouts of a flag in each environment.  It also holds the off
+// variation, which the api client turns into variation 0 when there is none.
+type flagBucketing struct {
+	Environments map[string]struct {
+		OffVariation *int32 `json:"offVariation"`
+		Fallthrough  struct {
+			Rollout *bucketedRollout `json:"rollout"`
+		} `json:"fallthrough"`
+		Rules []struct {
+			Rollout *bucketedRollout `json:"rollout"`
+		} `json:"rules"`
+	} `json:"environments"`
+}
+
+// bucketBy returns the bucketing attribute of the fallthrough (rule < 0) or a rule
+func (b *flagBucketing) bucketBy(env string, rule int) string {
+	if b == nil {
+		return ""
+	}
+	config, ok := b.Environments[env]
+	if !ok {
+		return ""
+	}
+	var rollout *bucketedRollout
+	if rule < 0 {
+		rollout = config.Fallthrough.Rollout
+	} else if rule < len(config.Rules) {
+		rollout = config.Rules[rule].Rollout
+	}
+	if rollout == nil {
+		return ""
+	}
+	return rollout.BucketBy
+}
+
+func getFlagBucketing(p perProjectPath) (*flagBucketing, error) {
+	var bucketing flagBucketing
+	err := api.GetJSON(getServer(p.Config()), getToken(p.Config()), fmt.Sprintf("/flags/%s/%s", p.Project(), p.Key()), &bucketing)
+	if err != nil {
+		return nil, err
+	}
+	return &bucketing, nil
+}
+
+func rolloutFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("rollout", pflag.ContinueOnError)
+	flags.String("rule", "", "index or _id of the targeting rule to set the rollout for instead of the fallthrough")
+	flags.String("bucket-by", "", "user attribute used to assign users to variations (default key)")
+	return flags
+}
+
+// validateRollout checks that a rollout has one weight for each variation of a flag and that they add up to 100%
+func validateRollout(variations []ldapi.WeightedVariation, variationCount int) error {
+	if len(variations) != variationCount {
+		return fmt.Errorf("rollout has %d weights but the flag has %d variations", len(variations), variationCount)
+	}
+	seen := make(map[int32]bool)
+	var total int32
+	for _, wv := range variations {
+		if wv.Variation < 0 || int(wv.Variation) >= variationCount {
+			return fmt.Errorf("variation %d does not exist", wv.Variation)
+		}
+		if seen[wv.Variation] {
+			return fmt.Errorf("variation %d has more than one weight", wv.Variation)
+		}
+		seen[wv.Variation] = true
+		if wv.Weight < 0 {
+			return fmt.Errorf("weight for variation %d is negative", wv.Variation)
+		}
+		total += wv.Weight
+	}
+	if total != rolloutTotalWeight {
+		return fmt.Errorf("weights add up to %s, not 100%%", formatWeight(total))
+	}
+	return nil
+}
+
+// findRule finds a targeting rule by _id or index
+func findRule(rules []ldapi.Rule, ref string) (int, error) {
+	for i, r := range rules {
+		if r.Id == ref {
+			return i, nil
+		}
+	}
+	if i, err := strconv.Atoi(ref); err == nil {
+		if i < 0 || i >= len(rules) {
+			return 0, fmt.Errorf("rule %d does not exist; the flag has %d rules", i, len(rules))
+		}
+		return i, nil
+	}
+	return 0, fmt.Errorf(`rule "%s" does not exist`, ref)
+}
+
+// rolloutPatches replaces the rollout of the fallthrough or rule at basePath, keeping its bucketing attribute unless
+// a new one is given
+func rolloutPatches(basePath string, hasRollout bool, variations []ldapi.WeightedVariation, bucketBy string) []ldapi.PatchOperation {
+	weights := toWeightedVariations(variations)
+	if !hasRollout {
+		return []ldapi.PatchOperation{
+			{Op: "remove", Path: basePath + "/variation"},
+			{Op: "add", Path: basePath + "/rollout", Value: interfacePtr(bucketedRollout{Variations: weights, BucketBy: bucketBy})},
+		}
+	}
+	patches := []ldapi.PatchOperation{{Op: "replace", Path: basePath + "/rollout/variations", Value: interfacePtr(weights)}}
+	if bucketBy != "" {
+		patches = append(patches, ldapi.PatchOperation{Op: "add", Path: basePath + "/rollout/bucketBy", Value: interfacePtr(bucketBy)})
+	}
+	return patches
+}
+
+// formatRollout formats the weights of a rollout in variation order
+func formatRollout(variationCount int, rollout *ldapi.Rollout) string {
+	weights := make([]string, variationCount)
+	for i := range weights {
+		weights[i] = "-"
+	}
+	for _, v := range rollout.Variations {
+		if int(v.Variation) < variationCount {
+			weights[v.Variation] = formatWeight(v.Weight)
+		}
+	}
+	return strings.Join(weights, "/")
+}
+
+func formatWeight(weight int32) string {
+	return fmt.Sprintf("%2.2f%%", float64(weight)/1000.0)
+}
+
+// renderFlagTargeting shows what each rule and the fallthrough serve in each environment
+func renderFlagTargeting(c *ishell.Context, flag ldapi.FeatureFlag, bucketing *flagBucketing) {
+	var envKeys []string
+	for envKey := range flag.Environments {
+		envKeys = append(envKeys, envKey)
+	}
+	sort.Strings(envKeys)
+
+	serves := func(variation int32, rollout *ldapi.Rollout) string {
+		if rollout != nil {
+			return formatRollout(len(flag.Variations), rollout)
+		}
+		return fmt.Sprintf("variation %d", variation)
+	}
+
+	buf := bytes.Buffer{}
+	table := tablewriter.NewWriter(&buf)
+	table.SetHeader([]string{"Environment", "Rule", "ID", "Serves", "Bucket By"})
+	for _, envKey := range envKeys {
+		config := flag.Environments[envKey]
+		for i, rule := range config.Rules {
+			table.Append([]string{envKey, strconv.Itoa(i), rule.Id, serves(rule.Variation, rule.Rollout), bucketing.bucketBy(envKey, i)})
+		}
+		if config.Fallthrough_ != nil {
+			table.Append([]string{envKey, "fallthrough", "", serves(config.Fallthrough_.Variation, config.Fallthrough_.Rollout),
+				bucketing.bucketBy(envKey, -1)})
+		}
+	}
+	table.Render()
+	c.Println("Targeting:")
+	c.Println(buf.String())
+}
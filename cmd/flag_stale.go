@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+	"github.com/spf13/pflag"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	"github.com/launchdarkly/ldc/api"
+	"github.com/launchdarkly/ldc/cmd/internal/path"
+	"github.com/launchdarkly/ldc/cmd/internal/stale"
+)
+
+// staleFlag joins a flag with its status in every environment of a project
+type staleFlag struct {
+	Key           string            `json:"key"`
+	Name          string            `json:"name"`
+	Status        string            `json:"status"`
+	Statuses      map[string]string `json:"statuses"`
+	LastRequested string            `json:"lastRequested,omitempty"`
+	Temporary     bool              `json:"temporary"`
+	Tags          []string          `json:"tags,omitempty"`
+	Maintainer    string            `json:"maintainer,omitempty"`
+	AgeDays       int               `json:"ageDays"`
+	References    *int              `json:"references,omitempty"`
+	Candidate     bool              `json:"candidate"`
+	Score         int               `json:"score"`
+}
+
+func staleFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("stale", pflag.ContinueOnError)
+	flags.String("format", formatTable, "output format: "+strings.Join(reportFormats, ", "))
+	flags.Bool("candidates", false, "only show cleanup candidates")
+	flags.String("refs", "", "count references to each flag in this source directory")
+	return flags
+}
+
+func showStaleFlags(c *ishell.Context) {
+	flags := staleFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	format, _ := flags.GetString("format")
+	onlyCandidates, _ := flags.GetBool("candidates")
+	refsDir, _ := flags.GetString("refs")
+
+	configKey := currentConfig
+	projectKey := currentProject
+	if len(c.Args) > 0 {
+		realPath, err := realProjPath(c.Args[0])
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		configKey = realPath.Config()
+		projectKey = realPath.Key()
+	}
+
+	var refCounts map[string]int
+	if refsDir != "" {
+		result, err := scanRefs(refsDir, path.NewAbsPath(configKey, projectKey).String(), "", nil)
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		refCounts = make(map[string]int)
+		for _, r := range result.References {
+			refCounts[r.Key]++
+		}
+	}
+
+	report, envKeys, err := getStaleFlags(configKey, projectKey, refCounts, time.Now())
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+
+	if onlyCandidates {
+		var candidates []staleFlag
+		for _, f := range report {
+			if f.Candidate {
+				candidates = append(candidates, f)
+			}
+		}
+		report = candidates
+	}
+
+	if renderJSON(c) {
+		printJSON(c, report)
+		return
+	}
+
+	header := []string{"Key", "Name", "Status"}
+	header = append(header, envKeys...)
+	header = append(header, "Last Requested", "Temporary", "Age (days)", "Maintainer", "Tags")
+	if refCounts != nil {
+		header = append(header, "References")
+	}
+	header = append(header, "Score")
+	var rows [][]string
+	for _, f := range report {
+		row := []string{f.Key, f.Name, f.Status}
+		for _, envKey := range envKeys {
+			row = append(row, f.Statuses[envKey])
+		}
+		row = append(row, f.LastRequested, strconv.FormatBool(f.Temporary), strconv.Itoa(f.AgeDays), f.Maintainer,
+			strings.Join(f.Tags, " "))
+		if f.References != nil {
+			row = append(row, strconv.Itoa(*f.References))
+		}
+		row = append(row, strconv.Itoa(f.Score))
+		rows = append(rows, row)
+	}
+	if err := renderReport(c, format, header, rows); err != nil {
+		reportError(c, err)
+	}
+}
+
+// getStaleFlags returns a report for every flag in the project, ranked with the best cleanup candidates first.
+// refCounts holds the number of source code references for each flag, if they are known.
+func getStaleFlags(configKey *string, projectKey string, refCounts map[string]int, now time.Time) ([]staleFlag, []string, error) {
+	flags, err := listFlags(configKey, projectKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	envs, err := listEnvironments(configKey, projectKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := api.GetClient(getServer(configKey))
+	if err != nil {
+		return nil, nil, err
+	}
+	auth := api.GetAuthCtx(getToken(configKey))
+
+	envKeys := keysForEnvironments(envs)
+	sort.Strings(envKeys)
+	statusesByEnv := make(map[string]map[string]ldapi.FeatureFlagStatus)
+	for _, envKey := range envKeys {
+		statuses, _, err := client.FeatureFlagsApi.GetFeatureFlagStatuses(auth, projectKey, envKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		statusesByEnv[envKey] = make(map[string]ldapi.FeatureFlagStatus)
+		for _, status := range statuses.Items {
+			statusesByEnv[envKey][flagKeyForStatus(status)] = status
+		}
+	}
+
+	var report []staleFlag
+	for _, flag := range flags {
+		f := staleFlag{
+			Key:        flag.Key,
+			Name:       flag.Name,
+			Statuses:   make(map[string]string),
+			Temporary:  flag.Temporary,
+			Tags:       flag.Tags,
+			Maintainer: flag.MaintainerId,
+		}
+		if flag.Maintainer != nil && flag.Maintainer.Email != "" {
+			f.Maintainer = flag.Maintainer.Email
+		}
+		if flag.CreationDate > 0 {
+			created := time.Unix(int64(flag.CreationDate)/1000, 0)
+			f.AgeDays = int(now.Sub(created).Hours() / 24)
+		}
+		for _, envKey := range envKeys {
+			status, ok := statusesByEnv[envKey][flag.Key]
+			if !ok {
+				continue
+			}
+			f.Statuses[envKey] = status.Name
+			if status.LastRequested > f.LastRequested {
+				f.LastRequested = status.LastRequested
+			}
+		}
+		if refCounts != nil {
+			count := refCounts[flag.Key]
+			f.References = &count
+		}
+		f.Status = stale.Classify(f.Statuses)
+		f.Candidate, f.Score = stale.Score(stale.Flag{
+			Statuses:   f.Statuses,
+			Temporary:  f.Temporary,
+			References: f.References,
+			AgeDays:    f.AgeDays,
+		})
+		report = append(report, f)
+	}
+
+	sort.SliceStable(report, func(i, j int) bool {
+		if report[i].Score != report[j].Score {
+			return report[i].Score > report[j].Score
+		}
+		return report[i].Key < report[j].Key
+	})
+	return report, envKeys, nil
+}
+
+// flagKeyForStatus extracts the flag key from the self link of a status, which is the only place it appears
+func flagKeyForStatus(status ldapi.FeatureFlagStatus) string {
+	if status.Links == nil || status.Links.Self == nil {
+		return ""
+	}
+	href := strings.TrimRight(status.Links.Self.Href, "/")
+	return href[strings.LastIndex(href, "/")+1:]
+}
@@ -2,7 +2,7 @@ package cmd
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
@@ -12,6 +12,7 @@ import (
 	ldapi "github.com/launchdarkly/api-client-go"
 
 	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/pflag"
 	ishell "gopkg.in/abiosoft/ishell.v2"
 
 	"github.com/launchdarkly/ldc/api"
@@ -43,6 +44,17 @@ func addGoalCommands(shell *ishell.Shell) {
 		Help: "Create new custom goal",
 		Func: createCustomGoal,
 	})
+	create.AddCmd(withFlags(&ishell.Cmd{
+		Name: "click",
+		Help: "Create new click goal: create click --selector <css selector> --exact <url> <name>",
+		Func: createClickGoal,
+	}, clickGoalFlagSet))
+	create.AddCmd(withFlags(&ishell.Cmd{
+		Name:    "pageview",
+		Aliases: []string{"page-view"},
+		Help:    "Create new page view goal: create pageview --substring <text> <name>",
+		Func:    createPageViewGoal,
+	}, pageViewGoalFlagSet))
 	root.AddCmd(create)
 
 	root.AddCmd(&ishell.Cmd{
@@ -52,12 +64,18 @@ func addGoalCommands(shell *ishell.Shell) {
 		Func:      showGoal,
 	})
 
-	root.AddCmd(&ishell.Cmd{
+	root.AddCmd(withFlags(&ishell.Cmd{
 		Name:      "results",
-		Help:      "show a goal's experiment results for a flag [show <goal name> <flag key>]",
+		Help:      "show experiment results for each variation of a flag, for one goal or for every goal of the flag with --flag: results <goal> <flag>",
 		Completer: detachGoalCompleter,
 		Func:      showExperimentResults,
-	})
+	}, resultsFlagSet))
+
+	root.AddCmd(withFlags(&ishell.Cmd{
+		Name: "plan",
+		Help: "compute the impressions an experiment needs: plan --baseline 4% --mde 5%",
+		Func: planExperiment,
+	}, planFlagSet))
 
 	root.AddCmd(&ishell.Cmd{
 		Name:      "attach",
@@ -71,12 +89,12 @@ func addGoalCommands(shell *ishell.Shell) {
 		Completer: detachGoalCompleter,
 		Func:      detachGoal,
 	})
-	root.AddCmd(&ishell.Cmd{
+	root.AddCmd(withFlags(&ishell.Cmd{
 		Name:      "edit",
-		Help:      "edit a goal's json in a text editor",
+		Help:      "edit a goal's json in a text editor.  edit [--yaml] <goal>",
 		Completer: goalCompleter,
 		Func:      editGoal,
-	})
+	}, editFlagSet))
 	root.AddCmd(&ishell.Cmd{
 		Name:      "delete",
 		Aliases:   []string{"remove"},
@@ -107,43 +125,48 @@ func getGoalArg(c *ishell.Context) (goalPath, *goalapi.Goal) {
 		var err error
 		realPath, err = realGoalPath(c.Args[0])
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return goalPath{}, nil
 		}
 
-		ctx, err := newGoalAPIContext(realPath.EnvPath())
+		client, err := newGoalClient(realPath.EnvPath())
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return goalPath{}, nil
 		}
 
-		goals, err := goalapi.GetGoals(ctx)
+		goals, err := listGoals(realPath.EnvPath(), client)
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return goalPath{}, nil
 		}
 
 		// match either id or name
 		for _, g := range goals {
 			if g.ID == realPath.ID() || g.Name == realPath.Key() {
-				goal, err = goalapi.GetGoal(ctx, g.ID)
+				goal, err = client.GetGoal(context.Background(), g.ID)
 				if err != nil {
-					c.Err(err)
+					reportError(c, err)
 					return goalPath{}, nil
 				}
 				break
 			}
 		}
+		if goal == nil {
+			reportError(c, errors.New("goal not found"))
+			return goalPath{}, nil
+		}
 	} else {
-		goal, err := chooseGoal(c, currentConfig, currentProject, currentEnvironment)
+		var err error
+		goal, err = chooseGoal(c, currentConfig, currentProject, currentEnvironment)
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return goalPath{}, nil
 		}
 
 		realPath, err = realGoalPath(*goal.Key)
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return goalPath{}, nil
 		}
 	}
@@ -153,22 +176,24 @@ func getGoalArg(c *ishell.Context) (goalPath, *goalapi.Goal) {
 
 func chooseGoal(c *ishell.Context, config *string, project string, env string) (goal *goalapi.Goal, err error) {
 	envPath := perProjectPath{ResourcePath: path.NewAbsPath(config, project, env)}
-	ctx, err := newGoalAPIContext(envPath)
+	client, err := newGoalClient(envPath)
 	if err != nil {
 		return nil, err
 	}
 
-	goals, _ := goalapi.GetGoals(ctx)
+	goals, err := listGoals(envPath, client)
+	if err != nil {
+		return nil, err
+	}
 	var options []string
 	for _, g := range goals {
 		options = append(options, g.Name)
 	}
-	choice := c.MultiChoice(options, "Choose a goal: ")
-	if choice < 0 {
+	choice, err := multiChoice(c, options, "Choose a goal: ")
+	if err != nil {
 		return nil, err
 	}
-	foundGoal, _ := goalapi.GetGoal(ctx, goals[choice].ID)
-	return foundGoal, nil
+	return client.GetGoal(context.Background(), goals[choice].ID)
 }
 
 func goalCompleter(args []string) (completions []string) {
@@ -187,13 +212,13 @@ var goalLister = path.ListerFunc(func(parentPath path.ResourcePath) ([]string, e
 
 func listGoalNames(configKey *string, projKey string, envKey string) ([]string, error) {
 	envPath := perProjectPath{ResourcePath: path.NewAbsPath(configKey, projKey, envKey)}
-	ctx, err := newGoalAPIContext(envPath)
+	client, err := newGoalClient(envPath)
 	if err != nil {
 		return nil, err
 	}
 
 	var keys []string
-	g, err := goalapi.GetGoals(ctx)
+	g, err := listGoals(envPath, client)
 	if err != nil {
 		return nil, err
 	}
@@ -206,7 +231,6 @@ func listGoalNames(configKey *string, projKey string, envKey string) ([]string,
 func showGoal(c *ishell.Context) {
 	_, goal := getGoalArg(c)
 	if goal == nil {
-		c.Println("Unknown goal")
 		return
 	}
 	renderGoal(c, goal)
@@ -216,7 +240,6 @@ func showGoals(c *ishell.Context) {
 	if len(c.Args) > 0 {
 		_, goal := getGoalArg(c)
 		if goal == nil {
-			c.Println("Unknown goal")
 			return
 		}
 		renderGoal(c, goal)
@@ -225,15 +248,15 @@ func showGoals(c *ishell.Context) {
 
 	envPath := perProjectPath{ResourcePath: path.NewAbsPath(currentConfig, currentProject, currentEnvironment)}
 
-	ctx, err := newGoalAPIContext(envPath)
+	client, err := newGoalClient(envPath)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 
-	goals, err := goalapi.GetGoals(ctx)
+	goals, err := listGoals(envPath, client)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 
@@ -247,40 +270,12 @@ func showGoals(c *ishell.Context) {
 	table.SetAutoWrapText(false)
 	table.Render()
 	if buf.Len() > 1000 {
-		c.Err(c.ShowPaged(buf.String()))
+		reportError(c, c.ShowPaged(buf.String()))
 	} else {
 		c.Print(buf.String())
 	}
 }
 
-func showExperimentResults(c *ishell.Context) {
-	p, goal := getGoalArg(c)
-	if goal == nil {
-		c.Println("Unknown goal")
-		return
-	}
-
-	_, flag := getFlagArg(c, 1)
-	if flag == nil {
-		c.Println("Unknown flag")
-		return
-	}
-
-	ctx, err := newGoalAPIContext(p.EnvPath())
-	if err != nil {
-		c.Err(err)
-		return
-	}
-
-	results, err := goalapi.GetExperimentResults(ctx, goal.ID, flag.Key)
-	if err != nil {
-		c.Err(err)
-		return
-	}
-
-	renderExperimentResults(c, results)
-}
-
 func renderGoal(c *ishell.Context, goal *goalapi.Goal) {
 	if renderJSON(c) {
 		printJSON(c, goal)
@@ -293,6 +288,17 @@ func renderGoal(c *ishell.Context, goal *goalapi.Goal) {
 	table.Append([]string{"Name", goal.Name})
 	table.Append([]string{"Description", goal.Description})
 	table.Append([]string{"Kind", goal.Kind})
+	if goal.Key != nil {
+		table.Append([]string{"Key", *goal.Key})
+	}
+	if goal.Selector != "" {
+		table.Append([]string{"Selector", goal.Selector})
+	}
+	for _, matchers := range goal.URLs {
+		for _, description := range matchers.Describe() {
+			table.Append([]string{"URL", description})
+		}
+	}
 	table.Append([]string{"Attached Flags", strconv.Itoa(goal.AttachedFeatureCount)})
 	table.SetAlignment(tablewriter.ALIGN_LEFT)
 	table.Render()
@@ -312,29 +318,61 @@ func renderGoal(c *ishell.Context, goal *goalapi.Goal) {
 }
 
 func editGoal(c *ishell.Context) {
+	format, ok := parseEditFlags(c)
+	if !ok {
+		return
+	}
 	p, goal := getGoalArg(c)
-	data, _ := json.MarshalIndent(goal, "", "    ")
-
-	patchComment, err := editFile(c, data)
+	if goal == nil {
+		return
+	}
+	data, err := marshalEdit(goal)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 
-	if patchComment == nil {
-		c.Println("No changes")
+	client, err := newGoalClient(p.EnvPath())
+	if err != nil {
+		reportError(c, err)
 		return
 	}
 
-	ctx, err := newGoalAPIContext(p.EnvPath())
+	changed, err := editResource(c, editTarget{
+		kind:     "goal",
+		newValue: func() interface{} { return &goalapi.Goal{} },
+		fetch: func() ([]byte, int, error) {
+			current, err := client.GetGoal(context.Background(), goal.ID)
+			if err != nil {
+				return nil, 0, err
+			}
+			data, err := marshalEdit(current)
+			return data, current.Version, err
+		},
+		patch: func(patchComment ldapi.PatchComment) error {
+			envs := []string{p.Environment()}
+			comment, err := getChangeComment(c, p.Config(), envs, patchComment.Comment)
+			if err != nil {
+				return err
+			}
+			patchComment.Comment = comment
+			if err := confirmChange(c, p.Config(), envs); err != nil {
+				return err
+			}
+			_, err = client.PatchGoal(context.Background(), goal.ID, patchComment)
+			if errors.Is(err, goalapi.ErrConflict) {
+				return errEditConflict
+			}
+			return err
+		},
+	}, format, data, goal.Version)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 
-	_, err = goalapi.PatchGoal(ctx, goal.ID, *patchComment)
-	if err != nil {
-		c.Err(err)
+	if !changed {
+		c.Println("No changes")
 		return
 	}
 
@@ -348,11 +386,15 @@ func createCustomGoal(c *ishell.Context) {
 		var err error
 		p, err = realGoalPath(c.Args[0])
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return
 		}
 		key = c.Args[1]
 	} else {
+		if !canPrompt() {
+			reportError(c, errNoTerminal)
+			return
+		}
 		c.Print("Name: ")
 		name := c.ReadLine()
 		c.Print("Key: ")
@@ -360,25 +402,100 @@ func createCustomGoal(c *ishell.Context) {
 		var err error
 		p, err = realGoalPath(name)
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return
 		}
 	}
-	goal := goalapi.Goal{
+	createGoal(c, p, goalapi.Goal{
 		Name: p.Key(),
-		Kind: "custom",
+		Kind: goalapi.Custom,
 		Key:  &key,
+	})
+}
+
+func urlMatcherFlagSet(name string) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.StringArray("exact", nil, "url the page must have, which may be repeated")
+	flags.StringArray("canonical", nil, "url the page must have, ignoring its query string and fragment")
+	flags.StringArray("substring", nil, "text the url of the page must contain")
+	flags.StringArray("regex", nil, "regular expression the url of the page must match")
+	return flags
+}
+
+func clickGoalFlagSet() *pflag.FlagSet {
+	flags := urlMatcherFlagSet("click")
+	flags.String("selector", "", "css selector of the elements to count clicks on, e.g. #checkout or .buy-button")
+	return flags
+}
+
+func pageViewGoalFlagSet() *pflag.FlagSet {
+	return urlMatcherFlagSet("pageview")
+}
+
+func createClickGoal(c *ishell.Context) {
+	flags := clickGoalFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	selector, _ := flags.GetString("selector")
+	if selector == "" {
+		reportError(c, errors.New("a css selector is required: create click --selector <css selector> --exact <url> <name>"))
+		return
+	}
+	createURLGoal(c, flags, goalapi.Goal{Kind: goalapi.Click, Selector: selector})
+}
+
+func createPageViewGoal(c *ishell.Context) {
+	flags := pageViewGoalFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	createURLGoal(c, flags, goalapi.Goal{Kind: goalapi.PageView})
+}
+
+// createURLGoal creates a click or page view goal for the urls given as options, named by the argument
+func createURLGoal(c *ishell.Context, flags *pflag.FlagSet, goal goalapi.Goal) {
+	exact, _ := flags.GetStringArray("exact")
+	canonical, _ := flags.GetStringArray("canonical")
+	substrings, _ := flags.GetStringArray("substring")
+	patterns, _ := flags.GetStringArray("regex")
+	matchers, err := goalapi.NewURLMatchers(exact, canonical, substrings, patterns)
+	if err != nil {
+		reportError(c, fmt.Errorf("%s: use --exact, --canonical, --substring or --regex", err))
+		return
+	}
+	goal.URLs = []goalapi.GoalURLMatchers{matchers}
+
+	var name string
+	if len(c.Args) > 0 {
+		name = c.Args[0]
+	} else {
+		if !canPrompt() {
+			reportError(c, errors.New("a goal name is required"))
+			return
+		}
+		c.Print("Name: ")
+		name = c.ReadLine()
+	}
+	p, err := realGoalPath(name)
+	if err != nil {
+		reportError(c, err)
+		return
 	}
+	goal.Name = p.Key()
+	createGoal(c, p, goal)
+}
 
-	ctx, err := newGoalAPIContext(p.EnvPath())
+func createGoal(c *ishell.Context, p goalPath, goal goalapi.Goal) {
+	client, err := newGoalClient(p.EnvPath())
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 
-	newGoal, err := goalapi.CreateGoal(ctx, goal)
+	newGoal, err := client.CreateGoal(context.Background(), goal)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 
@@ -393,19 +510,18 @@ func createCustomGoal(c *ishell.Context) {
 func deleteGoal(c *ishell.Context) {
 	p, goal := getGoalArg(c)
 	if goal == nil {
-		c.Err(errNotFound)
 		return
 	}
 
-	ctx, err := newGoalAPIContext(p.EnvPath())
+	client, err := newGoalClient(p.EnvPath())
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 
-	err = goalapi.DeleteGoal(ctx, goal.ID)
+	err = client.DeleteGoal(context.Background(), goal.ID)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 	} else {
 		c.Println("Deleted goal")
 	}
@@ -422,12 +538,10 @@ func attachGoal(c *ishell.Context) {
 	var flag *ldapi.FeatureFlag
 	goalPath, goal := getGoalArg(c)
 	if goal == nil {
-		c.Err(errors.New("goal not found"))
 		return
 	}
 	_, flag = getFlagArg(c, 1)
 	if flag == nil {
-		c.Err(errors.New("flag not found"))
 		return
 	}
 
@@ -442,15 +556,9 @@ func attachGoal(c *ishell.Context) {
 		Patch: []ldapi.PatchOperation{{Op: "add", Path: "/goalIds/-", Value: interfacePtr(goal.ID)}},
 	}
 
-	client, err := api.GetClient(getServer(goalPath.Config()))
-	if err != nil {
-		c.Err(err)
-		return
-	}
-	auth := api.GetAuthCtx(getToken(goalPath.Config()))
-	_, _, err = client.FeatureFlagsApi.PatchFeatureFlag(auth, currentProject, flag.Key, patchComment)
+	_, _, err := patchFlag(c, goalPath.Config(), currentProject, flag.Key, patchComment)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	c.Println("Goal was attached")
@@ -459,7 +567,13 @@ func attachGoal(c *ishell.Context) {
 func detachGoal(c *ishell.Context) {
 	var flag *ldapi.FeatureFlag
 	goalPath, goal := getGoalArg(c)
+	if goal == nil {
+		return
+	}
 	_, flag = getFlagArg(c, 1)
+	if flag == nil {
+		return
+	}
 
 	var pos *int
 	for p, g := range flag.GoalIds {
@@ -478,15 +592,9 @@ func detachGoal(c *ishell.Context) {
 		Patch: []ldapi.PatchOperation{{Op: "remove", Path: fmt.Sprintf("/goalIds/%d", *pos)}},
 	}
 
-	client, err := api.GetClient(getServer(goalPath.Config()))
+	_, _, err := patchFlag(c, goalPath.Config(), currentProject, flag.Key, patchComment)
 	if err != nil {
-		c.Err(err)
-		return
-	}
-	auth := api.GetAuthCtx(getToken(goalPath.Config()))
-	_, _, err = client.FeatureFlagsApi.PatchFeatureFlag(auth, currentProject, flag.Key, patchComment)
-	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	c.Println("Goal was detached")
@@ -513,16 +621,17 @@ func detachGoalCompleter(args []string) (completions []string) {
 		return nil
 	}
 
-	ctx, err := newGoalAPIContext(perProjectPath{path.NewAbsPath(currentConfig, currentProject, currentEnvironment)})
+	envPath := perProjectPath{path.NewAbsPath(currentConfig, currentProject, currentEnvironment)}
+	client, err := newGoalClient(envPath)
 	if err != nil {
 		return
 	}
 
-	goals, _ := goalapi.GetGoals(ctx)
+	goals, _ := listGoals(envPath, client)
 	goalKey := args[0]
 	for _, g := range goals {
 		if g.ID == goalKey || g.Name == goalKey {
-			goal, err := goalapi.GetGoal(ctx, g.ID)
+			goal, err := client.GetGoal(context.Background(), g.ID)
 			if err != nil {
 				return nil
 			}
@@ -535,40 +644,6 @@ func detachGoalCompleter(args []string) (completions []string) {
 	return completions
 }
 
-func renderExperimentResults(c *ishell.Context, results *goalapi.ExperimentResults) {
-	if renderJSON(c) {
-		data, err := json.MarshalIndent(results, "", " ")
-		if err != nil {
-			c.Err(err)
-			return
-		}
-		c.Println(string(data))
-		return
-	}
-
-	buf := bytes.NewBufferString("")
-	table := tablewriter.NewWriter(buf)
-	table.SetHeader([]string{"Change", "Confidence", "Z Score"})
-	table.Append([]string{floatToStr(results.Change), floatToStr(results.ConfidenceScore), floatToStr(results.ZScore)})
-	table.Render()
-	c.Print(buf.String())
-
-	buf = bytes.NewBufferString("")
-	table = tablewriter.NewWriter(buf)
-	table.SetHeader([]string{"Field", "Control", "Experiment"})
-	table.Append([]string{"Conversions", strconv.Itoa(results.Control.Conversions), strconv.Itoa(results.Experiment.Conversions)})
-	table.Append([]string{"Impressions", strconv.Itoa(results.Control.Impressions), strconv.Itoa(results.Experiment.Impressions)})
-	table.Append([]string{"Conversion Rate", floatToStr(results.Control.ConversionRate), floatToStr(results.Experiment.ConversionRate)})
-	table.Append([]string{"Confidence Interval", floatToStr(results.Control.ConfidenceInterval), floatToStr(results.Experiment.ConfidenceInterval)})
-	table.Append([]string{"Standard Error", floatToStr(results.Control.StandardError), floatToStr(results.Experiment.StandardError)})
-	table.Render()
-	c.Print(buf.String())
-}
-
-func floatToStr(f float64) string {
-	return fmt.Sprintf("%f", f)
-}
-
 func realGoalPath(rawPath string) (goalPath, error) {
 	p := toAbsPath(rawPath, currentConfig, currentProject, currentEnvironment)
 	if p.Depth() != 3 {
@@ -581,16 +656,31 @@ func realGoalPath(rawPath string) (goalPath, error) {
 	return goalPath{perEnvironmentPath{np}}, nil
 }
 
-func newGoalAPIContext(envPath perProjectPath) (goalapi.Context, error) {
+func newGoalClient(envPath perProjectPath) (*goalapi.Client, error) {
 	host := getServer(envPath.Config())
-	auth := api.GetAuthCtx(getToken(envPath.Config()))
-	client, err := api.GetClient(host)
-	if err != nil {
-		return goalapi.Context{}, err
-	}
-	env, _, err := client.EnvironmentsApi.GetEnvironment(auth, envPath.Project(), envPath.Key())
+	var env ldapi.Environment
+	err := cached(envPath.Config(), fmt.Sprintf("projects/%s/environments/%s", envPath.Project(), envPath.Key()), &env, func() error {
+		auth := api.GetAuthCtx(getToken(envPath.Config()))
+		client, err := api.GetClient(host)
+		if err != nil {
+			return err
+		}
+		env, _, err = client.EnvironmentsApi.GetEnvironment(auth, envPath.Project(), envPath.Key())
+		return err
+	})
 	if err != nil {
-		return goalapi.Context{}, err
+		return nil, err
 	}
-	return goalapi.NewContext(host, env.ApiKey), nil
+	return goalapi.NewClient(host, env.ApiKey), nil
+}
+
+// listGoals lists the goals of an environment
+func listGoals(envPath perProjectPath, client *goalapi.Client) ([]goalapi.Goal, error) {
+	var goals []goalapi.Goal
+	err := cached(envPath.Config(), fmt.Sprintf("projects/%s/environments/%s/goals", envPath.Project(), envPath.Key()), &goals, func() error {
+		var err error
+		goals, err = client.GetGoals(context.Background())
+		return err
+	})
+	return goals, err
 }
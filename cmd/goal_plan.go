@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/pflag"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	"github.com/launchdarkly/ldc/cmd/internal/stats"
+)
+
+const (
+	defaultAlpha = 0.05
+	defaultPower = 0.8
+	// defaultCheckMDE is the lift results are checked against when the planned lift is not given
+	defaultCheckMDE = "10%"
+)
+
+// experimentPlan is the number of impressions an experiment needs
+type experimentPlan struct {
+	Baseline                float64  `json:"baseline"`
+	MinimumDetectableLift   float64  `json:"minimumDetectableLift"`
+	Alpha                   float64  `json:"alpha"`
+	ComparisonAlpha         float64  `json:"comparisonAlpha"`
+	Power                   float64  `json:"power"`
+	Variations              int      `json:"variations"`
+	ImpressionsPerVariation int      `json:"impressionsPerVariation"`
+	TotalImpressions        int      `json:"totalImpressions"`
+	Days                    *float64 `json:"days,omitempty"`
+}
+
+// variationCheck tells whether the difference between a variation and the control is significant yet
+type variationCheck struct {
+	Goal                      string   `json:"goal"`
+	Flag                      string   `json:"flag"`
+	Variation                 string   `json:"variation"`
+	Impressions               int      `json:"impressions"`
+	ZScore                    *float64 `json:"zScore,omitempty"`
+	PValue                    *float64 `json:"pValue,omitempty"`
+	Significant               bool     `json:"significant"`
+	PlannedImpressions        int      `json:"plannedImpressions,omitempty"`
+	ImpressionsToSignificance *int     `json:"impressionsToSignificance,omitempty"`
+	HoursToSignificance       *float64 `json:"hoursToSignificance,omitempty"`
+	// Early is set when the difference is significant before the planned number of impressions, which is often
+	// chance when results are checked repeatedly
+	Early bool `json:"early"`
+}
+
+func planFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("plan", pflag.ContinueOnError)
+	flags.String("baseline", "", "conversion rate of the control, e.g. 4% or 0.04")
+	flags.String("mde", "", "minimum detectable effect, the smallest relative lift to detect, e.g. 5%")
+	flags.Float64("power", defaultPower, "probability of detecting the lift if there is one")
+	flags.Float64("alpha", defaultAlpha, "significance level, the chance of a false positive")
+	flags.Int("variations", 2, "number of variations, including the control")
+	flags.Int("daily-impressions", 0, "impressions per day across all variations, to estimate how long the experiment takes")
+	return flags
+}
+
+func planExperiment(c *ishell.Context) {
+	flags := planFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	if len(c.Args) > 0 {
+		reportError(c, errTooManyArgs)
+		return
+	}
+	rawBaseline, _ := flags.GetString("baseline")
+	rawMDE, _ := flags.GetString("mde")
+	power, _ := flags.GetFloat64("power")
+	alpha, _ := flags.GetFloat64("alpha")
+	variations, _ := flags.GetInt("variations")
+	dailyImpressions, _ := flags.GetInt("daily-impressions")
+	if rawBaseline == "" || rawMDE == "" {
+		reportError(c, errors.New("a baseline and minimum detectable effect are required: plan --baseline 4% --mde 5%"))
+		return
+	}
+	baseline, err := stats.ParseFraction(rawBaseline)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	mde, err := stats.ParseFraction(rawMDE)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	if variations < 2 {
+		reportError(c, errors.New("an experiment needs at least 2 variations"))
+		return
+	}
+
+	// each variation is compared with the control, so the chance of any false positive is kept to alpha
+	comparisonAlpha := alpha / float64(variations-1)
+	n, err := stats.SampleSize(baseline, mde, comparisonAlpha, power)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	plan := experimentPlan{
+		Baseline:                baseline,
+		MinimumDetectableLift:   mde,
+		Alpha:                   alpha,
+		ComparisonAlpha:         comparisonAlpha,
+		Power:                   power,
+		Variations:              variations,
+		ImpressionsPerVariation: n,
+		TotalImpressions:        n * variations,
+	}
+	if dailyImpressions > 0 {
+		days := float64(plan.TotalImpressions) / float64(dailyImpressions)
+		plan.Days = &days
+	}
+
+	if renderJSON(c) {
+		printJSON(c, plan)
+		return
+	}
+	buf := bytes.Buffer{}
+	table := tablewriter.NewWriter(&buf)
+	table.SetHeader([]string{"Field", "Value"})
+	table.Append([]string{"Baseline", formatPercent(&plan.Baseline)})
+	expected := baseline * (1 + mde)
+	table.Append([]string{"Detectable Rate", formatPercent(&expected)})
+	table.Append([]string{"Alpha", strconv.FormatFloat(alpha, 'g', -1, 64)})
+	if variations > 2 {
+		table.Append([]string{"Alpha Per Comparison", strconv.FormatFloat(comparisonAlpha, 'g', 4, 64)})
+	}
+	table.Append([]string{"Power", strconv.FormatFloat(power, 'g', -1, 64)})
+	table.Append([]string{"Impressions Per Variation", strconv.Itoa(n)})
+	table.Append([]string{"Total Impressions", strconv.Itoa(plan.TotalImpressions)})
+	if plan.Days != nil {
+		table.Append([]string{"Days", strconv.FormatFloat(math.Ceil(*plan.Days), 'f', 0, 64)})
+	}
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.Render()
+	c.Print(buf.String())
+}
+
+// checkExperimentResults recomputes whether each variation differs significantly from the control, and estimates
+// how long until it does from the rate of impressions so far
+func checkExperimentResults(c *ishell.Context, flags *pflag.FlagSet, flag *ldapi.FeatureFlag, format string, level float64,
+	results []experimentResult) {
+	rawMDE, _ := flags.GetString("mde")
+	power, _ := flags.GetFloat64("power")
+	started, _ := flags.GetString("started")
+	mde, err := stats.ParseFraction(rawMDE)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	start := time.Unix(int64(flag.CreationDate)/1000, 0)
+	if started != "" {
+		start, err = time.ParseInLocation("2006-01-02", started, time.Local)
+		if err != nil {
+			if start, err = time.Parse(time.RFC3339, started); err != nil {
+				reportError(c, fmt.Errorf(`invalid start "%s": use a date such as 2019-06-01 or a time such as 2019-06-01T09:00:00Z`, started))
+				return
+			}
+		}
+	}
+	elapsed := time.Since(start)
+
+	checks := checkResults(results, level, mde, power, elapsed)
+	if renderJSON(c) || format == formatJSON {
+		printJSON(c, checks)
+		return
+	}
+
+	header := []string{"Goal", "Flag", "Variation", "Impressions", "Z Score", "P Value", "Significant",
+		"Planned Impressions", "Impressions To Significance", "Time To Significance"}
+	var rows [][]string
+	for _, check := range checks {
+		row := []string{check.Goal, check.Flag, check.Variation, strconv.Itoa(check.Impressions),
+			formatFloat(check.ZScore), formatFloat(check.PValue), boolToCheck(check.Significant),
+			strconv.Itoa(check.PlannedImpressions)}
+		if check.ImpressionsToSignificance != nil {
+			row = append(row, strconv.Itoa(*check.ImpressionsToSignificance))
+		} else {
+			row = append(row, "")
+		}
+		switch {
+		case check.Significant:
+			row = append(row, "now")
+		case check.HoursToSignificance != nil:
+			row = append(row, formatHours(*check.HoursToSignificance))
+		default:
+			row = append(row, "unknown")
+		}
+		rows = append(rows, row)
+	}
+	if err := renderReport(c, format, header, rows); err != nil {
+		reportError(c, err)
+		return
+	}
+	if format == formatCSV {
+		return
+	}
+	for _, check := range checks {
+		if check.Early {
+			c.Printf("WARNING: %s is significant for %s after %d of the %d impressions planned to detect a %s lift.  "+
+				"Checking results repeatedly and stopping when they are significant finds differences that are not "+
+				"there, so wait for the planned impressions.\n",
+				check.Variation, check.Goal, check.Impressions, check.PlannedImpressions, rawMDE)
+		}
+	}
+}
+
+// checkResults checks each variation other than the control against the planned number of impressions.  As in
+// plan, the significance level 1 - level is split between the variations compared with the control for each goal.
+func checkResults(results []experimentResult, level float64, mde float64, power float64, elapsed time.Duration) []variationCheck {
+	controls := make(map[string]stats.Result)
+	comparisons := make(map[string]int)
+	for _, r := range results {
+		if r.Control {
+			controls[r.GoalID] = r.Result
+		} else {
+			comparisons[r.GoalID]++
+		}
+	}
+	var checks []variationCheck
+	for _, r := range results {
+		if r.Control {
+			continue
+		}
+		alpha := (1 - level) / float64(comparisons[r.GoalID])
+		check := variationCheck{Goal: r.Goal, Flag: r.Flag, Variation: r.Variation, Impressions: r.Impressions, ZScore: r.ZScore}
+		if r.ZScore != nil {
+			p := stats.PValue(*r.ZScore)
+			check.PValue = &p
+			check.Significant = p < alpha
+		}
+		if planned, err := stats.SampleSize(controls[r.GoalID].ConversionRate, mde, alpha, power); err == nil {
+			check.PlannedImpressions = planned
+			check.Early = check.Significant && r.Impressions < planned
+		}
+		if needed, ok := stats.ImpressionsToSignificance(r.Result, alpha); ok && !check.Significant {
+			check.ImpressionsToSignificance = &needed
+			if r.Impressions > 0 && elapsed > 0 {
+				hours := float64(needed-r.Impressions) / (float64(r.Impressions) / elapsed.Hours())
+				check.HoursToSignificance = &hours
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+func formatFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', 4, 64)
+}
+
+func formatHours(hours float64) string {
+	if hours < 48 {
+		return fmt.Sprintf("%.0f hours", math.Ceil(hours))
+	}
+	return fmt.Sprintf("%.0f days", math.Ceil(hours/24))
+}
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+	"github.com/spf13/pflag"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	"github.com/launchdarkly/ldc/cmd/internal/path"
+	"github.com/launchdarkly/ldc/cmd/internal/stats"
+	"github.com/launchdarkly/ldc/goalapi"
+)
+
+// experimentResult is the result of a variation of a flag for a goal
+type experimentResult struct {
+	Goal   string `json:"goal"`
+	GoalID string `json:"goalId"`
+	Flag   string `json:"flag"`
+	stats.Result
+}
+
+func resultsFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("results", pflag.ContinueOnError)
+	flags.String("flag", "", "show the results of every goal attached to this flag")
+	flags.String("control", "", "index or name of the variation to compare the others with (default the first)")
+	flags.Float64("confidence", stats.DefaultLevel, "confidence level of the intervals")
+	flags.String("format", formatTable, "output format: "+strings.Join(append(reportFormats, formatJSON), ", "))
+	flags.Bool("check", false, "check whether the results are significant yet and estimate how long until they are")
+	flags.String("mde", defaultCheckMDE, "with --check, the smallest lift the experiment was planned to detect, e.g. 5%")
+	flags.Float64("power", defaultPower, "with --check, the power the experiment was planned with")
+	flags.String("started", "", "with --check, when the experiment started, e.g. 2019-06-01 (default when the flag was created)")
+	return flags
+}
+
+func showExperimentResults(c *ishell.Context) {
+	flags := resultsFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	flagKey, _ := flags.GetString("flag")
+	control, _ := flags.GetString("control")
+	level, _ := flags.GetFloat64("confidence")
+	format, _ := flags.GetString("format")
+	check, _ := flags.GetBool("check")
+
+	var flag *ldapi.FeatureFlag
+	var goals []goalapi.Goal
+	var envPath perProjectPath
+	if flagKey != "" {
+		if len(c.Args) > 1 {
+			reportError(c, errTooManyArgs)
+			return
+		}
+		flagPath, err := realFlagPath(flagKey)
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		flag, err = getFlag(flagPath)
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		envPath = perProjectPath{path.NewAbsPath(flagPath.Config(), flagPath.Project(), currentEnvironment)}
+		if len(c.Args) > 0 {
+			// only the named goal
+			p, goal := getGoalArg(c)
+			if goal == nil {
+				return
+			}
+			envPath, goals = p.EnvPath(), []goalapi.Goal{*goal}
+		} else {
+			goals, err = getFlagGoals(envPath, flag)
+			if err != nil {
+				reportError(c, err)
+				return
+			}
+		}
+	} else {
+		p, goal := getGoalArg(c)
+		if goal == nil {
+			return
+		}
+		_, flag = getFlagArg(c, 1)
+		if flag == nil {
+			return
+		}
+		envPath, goals = p.EnvPath(), []goalapi.Goal{*goal}
+	}
+
+	results, err := getExperimentResults(envPath, flag, goals, control, level)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+
+	if check {
+		checkExperimentResults(c, flags, flag, format, level, results)
+		return
+	}
+	if renderJSON(c) || format == formatJSON {
+		printJSON(c, results)
+		return
+	}
+	if err := renderExperimentResults(c, format, level, results); err != nil {
+		reportError(c, err)
+	}
+}
+
+// getFlagGoals gets every goal attached to a flag
+func getFlagGoals(envPath perProjectPath, flag *ldapi.FeatureFlag) ([]goalapi.Goal, error) {
+	if len(flag.GoalIds) == 0 {
+		return nil, fmt.Errorf("flag %s has no goals", flag.Key)
+	}
+	client, err := newGoalClient(envPath)
+	if err != nil {
+		return nil, err
+	}
+	allGoals, err := listGoals(envPath, client)
+	if err != nil {
+		return nil, err
+	}
+	var goals []goalapi.Goal
+	for _, id := range flag.GoalIds {
+		goal := goalapi.Goal{ID: id, Name: id}
+		for _, g := range allGoals {
+			if g.ID == id {
+				goal = g
+				break
+			}
+		}
+		goals = append(goals, goal)
+	}
+	return goals, nil
+}
+
+// getExperimentResults gets the results of a flag for each of the goals and compares its variations
+func getExperimentResults(envPath perProjectPath, flag *ldapi.FeatureFlag, goals []goalapi.Goal, control string, level float64) ([]experimentResult, error) {
+	client, err := newGoalClient(envPath)
+	if err != nil {
+		return nil, err
+	}
+	var results []experimentResult
+	for _, goal := range goals {
+		goalResults, err := client.GetExperimentResults(context.Background(), goal.ID, flag.Key)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get results for goal %s: %w", goal.Name, err)
+		}
+		samples := experimentSamples(flag, goalResults)
+		controlIndex, err := sampleIndex(samples, control)
+		if err != nil {
+			return nil, err
+		}
+		compared, err := stats.Compare(samples, controlIndex, level)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range compared {
+			results = append(results, experimentResult{Goal: goal.Name, GoalID: goal.ID, Flag: flag.Key, Result: r})
+		}
+	}
+	return results, nil
+}
+
+// experimentSamples returns the samples of each variation.  Results without variations have only a control and an
+// experiment.
+func experimentSamples(flag *ldapi.FeatureFlag, results *goalapi.ExperimentResults) []stats.Sample {
+	if len(results.Variations) == 0 {
+		return []stats.Sample{
+			{Name: "control", Conversions: results.Control.Conversions, Impressions: results.Control.Impressions},
+			{Name: "experiment", Conversions: results.Experiment.Conversions, Impressions: results.Experiment.Impressions},
+		}
+	}
+	samples := make([]stats.Sample, len(results.Variations))
+	for i, v := range results.Variations {
+		name := strconv.Itoa(i)
+		if i < len(flag.Variations) {
+			name = variationLabel(flag.Variations[i])
+		}
+		samples[i] = stats.Sample{Name: name, Conversions: v.Conversions, Impressions: v.Impressions}
+	}
+	return samples
+}
+
+// variationLabel returns the name of a variation, or its value if it has no name
+func variationLabel(v ldapi.Variation) string {
+	if v.Name != "" {
+		return v.Name
+	}
+	if v.Value != nil {
+		if s, ok := (*v.Value).(string); ok {
+			return s
+		}
+	}
+	value, _ := json.Marshal(v.Value)
+	return string(value)
+}
+
+// sampleIndex finds a sample by index or name, defaulting to the first
+func sampleIndex(samples []stats.Sample, s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if i, err := strconv.Atoi(s); err == nil {
+		return i, nil
+	}
+	for i, sample := range samples {
+		if sample.Name == s {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf(`variation "%s" does not exist`, s)
+}
+
+func renderExperimentResults(c *ishell.Context, format string, level float64, results []experimentResult) error {
+	if len(results) == 0 {
+		return errors.New("no results")
+	}
+	interval := fmt.Sprintf("%g%% CI", level*100)
+	if format == formatCSV {
+		// csv is for analysis, so it has the numbers rather than percentages
+		header := []string{"Goal", "Goal ID", "Flag", "Variation", "Control", "Conversions", "Impressions",
+			"Conversion Rate", "Rate Low", "Rate High", "Lift", "Lift Low", "Lift High", "Z Score", "Confidence"}
+		var rows [][]string
+		for _, r := range results {
+			row := []string{r.Goal, r.GoalID, r.Flag, r.Variation, strconv.FormatBool(r.Control),
+				strconv.Itoa(r.Conversions), strconv.Itoa(r.Impressions), formatNumber(&r.ConversionRate),
+				formatNumber(&r.RateInterval.Low), formatNumber(&r.RateInterval.High), formatNumber(r.Lift)}
+			if r.LiftInterval != nil {
+				row = append(row, formatNumber(&r.LiftInterval.Low), formatNumber(&r.LiftInterval.High))
+			} else {
+				row = append(row, "", "")
+			}
+			rows = append(rows, append(row, formatNumber(r.ZScore), formatNumber(r.Confidence)))
+		}
+		return renderReport(c, format, header, rows)
+	}
+
+	header := []string{"Goal", "Flag", "Variation", "Conversions", "Impressions", "Conversion Rate", interval, "Lift",
+		"Lift " + interval, "Confidence"}
+	var rows [][]string
+	for _, r := range results {
+		lift := formatPercent(r.Lift)
+		if r.Control {
+			lift = "control"
+		}
+		liftInterval := ""
+		if r.LiftInterval != nil {
+			liftInterval = formatInterval(r.LiftInterval.Low, r.LiftInterval.High)
+		}
+		rows = append(rows, []string{r.Goal, r.Flag, r.Variation, strconv.Itoa(r.Conversions),
+			strconv.Itoa(r.Impressions), formatPercent(&r.ConversionRate),
+			formatInterval(r.RateInterval.Low, r.RateInterval.High), lift, liftInterval, formatPercent(r.Confidence)})
+	}
+	return renderReport(c, format, header, rows)
+}
+
+func formatNumber(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', -1, 64)
+}
+
+func formatPercent(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.2f%%", *f*100)
+}
+
+func formatInterval(low, high float64) string {
+	return fmt.Sprintf("%s to %s", formatPercent(&low), formatPercent(&high))
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+
+	"github.com/launchdarkly/ldc/api"
+	"github.com/launchdarkly/ldc/cmd/internal/guard"
+)
+
+// getConfigName returns the name of a config, or of the current one if the key is nil
+func getConfigName(configKey *string) string {
+	if configKey != nil {
+		return *configKey
+	}
+	if currentConfig != nil {
+		return *currentConfig
+	}
+	return ""
+}
+
+func (c config) isProtected(env string) bool {
+	for _, protected := range c.ProtectedEnvironments {
+		if protected == env {
+			return true
+		}
+	}
+	return false
+}
+
+// changeComment returns the comment to record with a change, which is the one given with --comment unless the
+// command has its own
+func changeComment(comment string) string {
+	if comment != "" {
+		return comment
+	}
+	return viper.GetString("comment")
+}
+
+// getChangeComment returns the comment to record with a change to the given environments, or to a whole flag if there
+// are none.  A comment is required for protected environments and by the requireComment and commentPattern settings
+// of the config.  In the shell, the user is asked for a comment that is missing or does not match the pattern.
+func getChangeComment(c *ishell.Context, configKey *string, envs []string, comment string) (string, error) {
+	cfg := configFile[getConfigName(configKey)]
+	required := cfg.RequireComment || cfg.CommentPattern != ""
+	for _, env := range envs {
+		required = required || cfg.isProtected(env)
+	}
+	var pattern *regexp.Regexp
+	if cfg.CommentPattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(cfg.CommentPattern); err != nil {
+			return "", fmt.Errorf(`invalid commentPattern for config "%s": %s`, getConfigName(configKey), err)
+		}
+	}
+
+	comment = changeComment(comment)
+	for {
+		var problem string
+		switch {
+		case !required:
+			return comment, nil
+		case strings.TrimSpace(comment) == "":
+			problem = "A comment is required"
+		case pattern != nil && !pattern.MatchString(comment):
+			problem = fmt.Sprintf("The comment must match %s", pattern)
+		default:
+			return comment, nil
+		}
+		if !isInteractive(c) || !canPrompt() {
+			return "", fmt.Errorf("%s for this change; use --comment", strings.ToLower(problem[:1])+problem[1:])
+		}
+		c.Printf("%s.  Enter comment (blank to cancel): ", problem)
+		if comment = c.ReadLine(); comment == "" {
+			return "", errAborted
+		}
+	}
+}
+
+// confirmChange asks the user to confirm changes to protected environments by re-entering their keys.  --yes confirms
+// them without asking and is required outside the shell.
+func confirmChange(c *ishell.Context, configKey *string, envs []string) error {
+	cfg := configFile[getConfigName(configKey)]
+	for _, env := range envs {
+		// nothing is changed in a dry run, so there is nothing to confirm
+		if !cfg.isProtected(env) || viper.GetBool("yes") || api.DryRun {
+			continue
+		}
+		if !isInteractive(c) || !canPrompt() {
+			return fmt.Errorf(`environment "%s" is protected; use --yes to confirm changes to it`, env)
+		}
+		c.Printf(`Environment "%s" is protected.  Re-enter its key to confirm the change: `, env)
+		if c.ReadLine() != env {
+			return errAborted
+		}
+	}
+	return nil
+}
+
+// checkRolloutSteps fails if a patch to a flag changes what its fallthrough or a rule serves by more than the
+// maxRolloutStep of the config
+func checkRolloutSteps(configKey *string, project, key string, ops []ldapi.PatchOperation) error {
+	limit := configFile[getConfigName(configKey)].MaxRolloutStep
+	if limit <= 0 || len(guard.Environments(ops)) == 0 {
+		return nil
+	}
+	var before interface{}
+	if err := api.GetJSON(getServer(configKey), getToken(configKey), fmt.Sprintf("/flags/%s/%s", project, key), &before); err != nil {
+		return err
+	}
+	after, err := guard.Apply(before, ops)
+	if err != nil {
+		return fmt.Errorf("unable to check the size of the change: %w", err)
+	}
+	for _, step := range guard.Steps(before, after) {
+		if step.Percent() > limit {
+			return fmt.Errorf(`the change to the %s in "%s" moves %v%% of users, more than the maxRolloutStep of %v%% for config "%s"`,
+				stepTarget(step), step.Environment, step.Percent(), limit, getConfigName(configKey))
+		}
+	}
+	return nil
+}
+
+func stepTarget(step guard.Step) string {
+	if step.Target == "fallthrough" {
+		return step.Target
+	}
+	return fmt.Sprintf("rule %s", step.Target)
+}
+
+// patchFlag sends a patch to a flag once it passes the guardrails of its config.  All changes to flags go through
+// here, except for ramps and clones, which are confirmed when they start.
+func patchFlag(c *ishell.Context, configKey *string, project, key string, patchComment ldapi.PatchComment) (ldapi.FeatureFlag, *http.Response, error) {
+	envs := guard.Environments(patchComment.Patch)
+	comment, err := getChangeComment(c, configKey, envs, patchComment.Comment)
+	if err != nil {
+		return ldapi.FeatureFlag{}, nil, err
+	}
+	patchComment.Comment = comment
+	if err := confirmChange(c, configKey, envs); err != nil {
+		return ldapi.FeatureFlag{}, nil, err
+	}
+	if err := checkRolloutSteps(configKey, project, key, patchComment.Patch); err != nil {
+		return ldapi.FeatureFlag{}, nil, err
+	}
+	return sendFlagPatch(configKey, project, key, patchComment)
+}
+
+// sendFlagPatch sends a patch to a flag without checking the guardrails
+func sendFlagPatch(configKey *string, project, key string, patchComment ldapi.PatchComment) (ldapi.FeatureFlag, *http.Response, error) {
+	client, err := api.GetClient(getServer(configKey))
+	if err != nil {
+		return ldapi.FeatureFlag{}, nil, err
+	}
+	auth := api.GetAuthCtx(getToken(configKey))
+	return client.FeatureFlagsApi.PatchFeatureFlag(auth, project, key, patchComment)
+}
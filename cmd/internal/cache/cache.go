@@ -0,0 +1,134 @@
+// Package cache holds api responses for a limited time, in memory and optionally on disk so that separate commands,
+// such as shell completions, can share them
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache holds values by key until they expire
+type Cache struct {
+	dir     string
+	mutex   sync.Mutex
+	entries map[string]entry
+	now     func() time.Time
+}
+
+type entry struct {
+	Key     string          `json:"key"`
+	Expires time.Time       `json:"expires"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// New creates a cache.  Values are also stored in dir unless it is empty.
+func New(dir string) *Cache {
+	return &Cache{dir: dir, entries: make(map[string]entry), now: time.Now}
+}
+
+// Get decodes the value for a key into result, returning false if there is no value or it has expired
+func (c *Cache) Get(key string, result interface{}) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	e, ok := c.entries[key]
+	if !ok && c.dir != "" {
+		e, ok = c.read(key)
+	}
+	if !ok || !c.now().Before(e.Expires) {
+		return false
+	}
+	c.entries[key] = e
+	return json.Unmarshal(e.Value, result) == nil
+}
+
+// Set stores a value for a key until ttl has passed
+func (c *Cache) Set(key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	e := entry{Key: key, Expires: c.now().Add(ttl), Value: data}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries[key] = e
+	if c.dir == "" {
+		return nil
+	}
+	return c.write(e)
+}
+
+// Clear removes every value
+func (c *Cache) Clear() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = make(map[string]entry)
+	if c.dir == "" {
+		return nil
+	}
+	files, err := ioutil.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".json") {
+			if err := os.Remove(filepath.Join(c.dir, file.Name())); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// filename hashes the key, which may contain anything
+func (c *Cache) filename(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *Cache) read(key string) (entry, bool) {
+	data, err := ioutil.ReadFile(c.filename(key))
+	if err != nil {
+		return entry{}, false
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || e.Key != key {
+		return entry{}, false
+	}
+	return e, true
+}
+
+func (c *Cache) write(e entry) error {
+	// values may include environment keys, so only the user can read them
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(c.dir, "tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+	// renaming means other commands never read a partly written file
+	return os.Rename(file.Name(), c.filename(e.Key))
+}
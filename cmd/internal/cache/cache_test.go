@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type value struct {
+	Keys []string
+}
+
+func TestMemory(t *testing.T) {
+	now := time.Now()
+	c := New("")
+	c.now = func() time.Time { return now }
+
+	var result value
+	assert.False(t, c.Get("flags", &result))
+	require.NoError(t, c.Set("flags", value{Keys: []string{"a", "b"}}, time.Minute))
+	assert.True(t, c.Get("flags", &result))
+	assert.Equal(t, []string{"a", "b"}, result.Keys)
+
+	now = now.Add(time.Minute)
+	assert.False(t, c.Get("flags", &result), "expired")
+
+	require.NoError(t, c.Set("flags", value{}, time.Minute))
+	require.NoError(t, c.Clear())
+	assert.False(t, c.Get("flags", &result), "cleared")
+}
+
+func TestDisk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, New(dir).Set("//config/projects", value{Keys: []string{"a"}}, time.Minute))
+
+	var result value
+	other := New(dir)
+	assert.True(t, other.Get("//config/projects", &result), "shared with another cache")
+	assert.Equal(t, []string{"a"}, result.Keys)
+	assert.False(t, other.Get("//config/flags", &result))
+
+	require.NoError(t, New(dir).Clear())
+	assert.False(t, New(dir).Get("//config/projects", &result), "cleared")
+	assert.NoError(t, New(dir+"/missing").Clear())
+}
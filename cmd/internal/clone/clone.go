@@ -0,0 +1,252 @@
+// Package clone plans copying a project and keeps track of the progress of a copy in state files so that it can be
+// resumed after a failure
+package clone
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+
+	"github.com/launchdarkly/ldc/goalapi"
+)
+
+// Kinds of steps
+const (
+	KindProject     = "project"
+	KindEnvironment = "environment"
+	KindSegment     = "segment"
+	KindGoal        = "goal"
+	KindFlag        = "flag"
+	KindTargeting   = "targeting"
+	KindFlagGoals   = "flag-goals"
+)
+
+// targetingFields are the fields of a flag's environment configuration that are copied, in the order they are set
+var targetingFields = []string{"on", "prerequisites", "targets", "rules", "fallthrough", "offVariation", "trackEvents",
+	"trackEventsFallthrough"}
+
+// segmentFields are the fields of a segment that are copied after it is created
+var segmentFields = []string{"included", "excluded", "rules"}
+
+// Options are the optional parts of a project to copy
+type Options struct {
+	Targeting bool `json:"targeting"`
+	Segments  bool `json:"segments"`
+	Goals     bool `json:"goals"`
+}
+
+// Source describes what there is to copy from the source project
+type Source struct {
+	Environments []string
+	Flags        []string
+	// Segments are the keys of the segments of each environment
+	Segments map[string][]string
+	// Goals are the ids of the goals of each environment
+	Goals map[string][]string
+	// GoalFlags are the flags with goals attached
+	GoalFlags []string
+}
+
+// Step is a single change made by a copy.  Environment is set for steps that copy something from one environment.
+type Step struct {
+	Kind        string `json:"kind"`
+	Environment string `json:"environment,omitempty"`
+	Key         string `json:"key,omitempty"`
+}
+
+// ID identifies the step in the state of a copy
+func (s Step) ID() string {
+	parts := []string{s.Kind}
+	if s.Environment != "" {
+		parts = append(parts, s.Environment)
+	}
+	if s.Key != "" {
+		parts = append(parts, s.Key)
+	}
+	return strings.Join(parts, "/")
+}
+
+// Plan returns the steps to copy a project.  Environments come first so that segments and goals can be created in
+// them, and every flag is created before any targeting so that prerequisites and segment rules refer to things that
+// exist.
+func Plan(source Source, options Options) []Step {
+	steps := []Step{{Kind: KindProject}}
+	for _, env := range source.Environments {
+		steps = append(steps, Step{Kind: KindEnvironment, Key: env})
+	}
+	if options.Segments {
+		for _, env := range source.Environments {
+			for _, key := range source.Segments[env] {
+				steps = append(steps, Step{Kind: KindSegment, Environment: env, Key: key})
+			}
+		}
+	}
+	if options.Goals {
+		for _, env := range source.Environments {
+			for _, id := range source.Goals[env] {
+				steps = append(steps, Step{Kind: KindGoal, Environment: env, Key: id})
+			}
+		}
+	}
+	for _, key := range source.Flags {
+		steps = append(steps, Step{Kind: KindFlag, Key: key})
+	}
+	if options.Targeting {
+		for _, key := range source.Flags {
+			for _, env := range source.Environments {
+				steps = append(steps, Step{Kind: KindTargeting, Environment: env, Key: key})
+			}
+		}
+	}
+	if options.Goals {
+		for _, key := range source.GoalFlags {
+			steps = append(steps, Step{Kind: KindFlagGoals, Key: key})
+		}
+	}
+	return steps
+}
+
+// State is the progress of a copy
+type State struct {
+	// Source and Destination are the paths of the projects
+	Source      string  `json:"source"`
+	Destination string  `json:"destination"`
+	Options     Options `json:"options"`
+	// Done are the ids of the steps that have been completed
+	Done []string `json:"done"`
+	// GoalIDs maps the ids of the source goals to the ids of their copies
+	GoalIDs   map[string]string `json:"goalIds,omitempty"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+// IsDone returns true if the step has been completed
+func (s *State) IsDone(step Step) bool {
+	id := step.ID()
+	for _, done := range s.Done {
+		if done == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Complete records that a step has been completed
+func (s *State) Complete(step Step) {
+	if !s.IsDone(step) {
+		s.Done = append(s.Done, step.ID())
+	}
+}
+
+// Load reads a state file, returning nil if it does not exist
+func Load(filename string) (*State, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid clone state in %s: %s", filename, err)
+	}
+	return &state, nil
+}
+
+// Save writes a state file, replacing it atomically so an interrupted copy never leaves a partial file
+func (s *State) Save(filename string) error {
+	s.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// TargetingPatch returns the changes that give a flag in an environment the same targeting as config, which is the
+// configuration of the source flag in that environment as returned by the api.  Fields are read from the raw
+// response because the api client drops variations and weights that are zero.
+func TargetingPatch(env string, config map[string]interface{}) []ldapi.PatchOperation {
+	return replaceFields(fmt.Sprintf("/environments/%s/", env), targetingFields, config)
+}
+
+// SegmentPatch returns the changes that give a new segment the same users and rules as segment, as returned by the
+// api
+func SegmentPatch(segment map[string]interface{}) []ldapi.PatchOperation {
+	return replaceFields("/", segmentFields, segment)
+}
+
+func replaceFields(prefix string, fields []string, source map[string]interface{}) []ldapi.PatchOperation {
+	var patch []ldapi.PatchOperation
+	for _, field := range fields {
+		value, ok := source[field]
+		if !ok || value == nil {
+			continue
+		}
+		// ids of rules and clauses belong to the source and are assigned again
+		value = withoutIDs(value)
+		patch = append(patch, ldapi.PatchOperation{Op: "replace", Path: prefix + field, Value: &value})
+	}
+	return patch
+}
+
+func withoutIDs(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			if k != "_id" {
+				result[k] = withoutIDs(item)
+			}
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = withoutIDs(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// RemapGoalIDs returns the ids of the copies of the given goals, leaving out goals that were not copied
+func RemapGoalIDs(ids []string, goalIDs map[string]string) []string {
+	result := []string{}
+	for _, id := range ids {
+		if newID, ok := goalIDs[id]; ok {
+			result = append(result, newID)
+		}
+	}
+	return result
+}
+
+// FindGoal returns the id of the goal among goals that is a copy of goal: the goal with the same key, or for goals
+// without a key, the goal with the same name and kind.  Goals can be created more than once with the same key and
+// name, so a clone that failed after creating a goal looks it up instead of creating it again.
+func FindGoal(goals []goalapi.Goal, goal goalapi.Goal) (string, bool) {
+	for _, g := range goals {
+		if goal.Key != nil {
+			if g.Key != nil && *g.Key == *goal.Key {
+				return g.ID, true
+			}
+		} else if g.Key == nil && g.Name == goal.Name && g.Kind == goal.Kind {
+			return g.ID, true
+		}
+	}
+	return "", false
+}
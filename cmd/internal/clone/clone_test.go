@@ -0,0 +1,124 @@
+package clone
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/launchdarkly/ldc/goalapi"
+)
+
+func stepIDs(steps []Step) (ids []string) {
+	for _, s := range steps {
+		ids = append(ids, s.ID())
+	}
+	return ids
+}
+
+func TestPlan(t *testing.T) {
+	source := Source{
+		Environments: []string{"production", "test"},
+		Flags:        []string{"a", "b"},
+		Segments:     map[string][]string{"production": {"beta"}},
+		Goals:        map[string][]string{"test": {"g1"}},
+		GoalFlags:    []string{"b"},
+	}
+	assert.Equal(t, []string{"project", "environment/production", "environment/test", "flag/a", "flag/b"},
+		stepIDs(Plan(source, Options{})))
+	assert.Equal(t, []string{"project", "environment/production", "environment/test",
+		"segment/production/beta", "goal/test/g1", "flag/a", "flag/b",
+		"targeting/production/a", "targeting/test/a", "targeting/production/b", "targeting/test/b",
+		"flag-goals/b"},
+		stepIDs(Plan(source, Options{Targeting: true, Segments: true, Goals: true})))
+}
+
+func TestTargetingPatch(t *testing.T) {
+	config := map[string]interface{}{
+		"on":           true,
+		"salt":         "abc",
+		"offVariation": float64(0),
+		"rules": []interface{}{
+			map[string]interface{}{"_id": "r1", "variation": float64(0),
+				"clauses": []interface{}{map[string]interface{}{"_id": "c1", "attribute": "key", "op": "in", "values": []interface{}{"a"}}}},
+		},
+		"fallthrough":   map[string]interface{}{"variation": float64(1)},
+		"prerequisites": nil,
+	}
+	patch := TargetingPatch("test", config)
+	var paths []string
+	for _, op := range patch {
+		assert.Equal(t, "replace", op.Op)
+		paths = append(paths, op.Path)
+	}
+	assert.Equal(t, []string{"/environments/test/on", "/environments/test/rules", "/environments/test/fallthrough",
+		"/environments/test/offVariation"}, paths)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"variation": float64(0),
+			"clauses": []interface{}{map[string]interface{}{"attribute": "key", "op": "in", "values": []interface{}{"a"}}}},
+	}, *patch[1].Value)
+	assert.Equal(t, float64(0), *patch[3].Value, "zero variations are kept")
+	assert.Equal(t, "r1", config["rules"].([]interface{})[0].(map[string]interface{})["_id"], "source is unchanged")
+}
+
+func TestSegmentPatch(t *testing.T) {
+	patch := SegmentPatch(map[string]interface{}{"key": "beta", "included": []interface{}{"u1"}})
+	require.Len(t, patch, 1)
+	assert.Equal(t, ldapi.PatchOperation{Op: "replace", Path: "/included", Value: patch[0].Value}, patch[0])
+	assert.Equal(t, []interface{}{"u1"}, *patch[0].Value)
+}
+
+func TestRemapGoalIDs(t *testing.T) {
+	assert.Equal(t, []string{"n1"}, RemapGoalIDs([]string{"g1", "g2"}, map[string]string{"g1": "n1"}))
+	assert.Equal(t, []string{}, RemapGoalIDs(nil, nil))
+}
+
+func TestFindGoal(t *testing.T) {
+	key, other := "signup", "checkout"
+	goals := []goalapi.Goal{
+		{ID: "g1", Name: "Signup", Kind: "custom", Key: &other},
+		{ID: "g2", Name: "Signup", Kind: "custom", Key: &key},
+		{ID: "g3", Name: "Pricing", Kind: "pageview"},
+	}
+
+	id, ok := FindGoal(goals, goalapi.Goal{Name: "Renamed", Kind: "custom", Key: &key})
+	assert.True(t, ok)
+	assert.Equal(t, "g2", id)
+
+	id, ok = FindGoal(goals, goalapi.Goal{Name: "Pricing", Kind: "pageview"})
+	assert.True(t, ok)
+	assert.Equal(t, "g3", id)
+
+	_, ok = FindGoal(goals, goalapi.Goal{Name: "Pricing", Kind: "click"})
+	assert.False(t, ok)
+}
+
+func TestState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clone")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "clones", "state.json")
+
+	state, err := Load(filename)
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	state = &State{Source: "src", Destination: "dst", GoalIDs: map[string]string{"g1": "n1"}}
+	step := Step{Kind: KindFlag, Key: "a"}
+	assert.False(t, state.IsDone(step))
+	state.Complete(step)
+	state.Complete(step)
+	assert.True(t, state.IsDone(step))
+	assert.Equal(t, []string{"flag/a"}, state.Done)
+	require.NoError(t, state.Save(filename))
+
+	loaded, err := Load(filename)
+	require.NoError(t, err)
+	assert.Equal(t, state.Done, loaded.Done)
+	assert.Equal(t, state.GoalIDs, loaded.GoalIDs)
+	assert.True(t, loaded.IsDone(step))
+}
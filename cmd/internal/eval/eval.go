@@ -0,0 +1,330 @@
+// Package eval evaluates flags for a user locally, following the rules used by the LaunchDarkly server-side sdks
+package eval
+
+import (
+	"crypto/sha1" // nolint:gosec // G505: sha1 is what the sdks use for bucketing
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+)
+
+// Reason kinds describe why a variation was chosen
+const (
+	ReasonOff                = "OFF"
+	ReasonTargetMatch        = "TARGET_MATCH"
+	ReasonRuleMatch          = "RULE_MATCH"
+	ReasonFallthrough        = "FALLTHROUGH"
+	ReasonPrerequisiteFailed = "PREREQUISITE_FAILED"
+	ReasonError              = "ERROR"
+)
+
+// Error kinds describe why an evaluation failed
+const (
+	ErrorMalformedFlag = "MALFORMED_FLAG"
+	ErrorFlagNotFound  = "FLAG_NOT_FOUND"
+	ErrorUserNotFound  = "USER_NOT_SPECIFIED"
+)
+
+const longScale = float64(0xFFFFFFFFFFFFFFF)
+
+// User holds the attributes of a user as they would be sent by an sdk.  Custom attributes may be nested under "custom".
+type User map[string]interface{}
+
+// Reason explains the result of an evaluation
+type Reason struct {
+	Kind            string `json:"kind"`
+	RuleIndex       *int   `json:"ruleIndex,omitempty"`
+	RuleID          string `json:"ruleId,omitempty"`
+	PrerequisiteKey string `json:"prerequisiteKey,omitempty"`
+	ErrorKind       string `json:"errorKind,omitempty"`
+}
+
+// String returns a human readable reason
+func (r Reason) String() string {
+	switch r.Kind {
+	case ReasonRuleMatch:
+		s := fmt.Sprintf("%s (rule %d", r.Kind, *r.RuleIndex)
+		if r.RuleID != "" {
+			s += ", id " + r.RuleID
+		}
+		return s + ")"
+	case ReasonPrerequisiteFailed:
+		return fmt.Sprintf("%s (%s)", r.Kind, r.PrerequisiteKey)
+	case ReasonError:
+		return fmt.Sprintf("%s (%s)", r.Kind, r.ErrorKind)
+	}
+	return r.Kind
+}
+
+// Result is the outcome of evaluating a flag
+type Result struct {
+	VariationIndex *int        `json:"variationIndex"`
+	Value          interface{} `json:"value"`
+	Reason         Reason      `json:"reason"`
+}
+
+// Source provides the flags and segments referenced by a flag
+type Source interface {
+	GetFlag(key string) (*ldapi.FeatureFlag, error)
+	GetSegment(key string) (*ldapi.UserSegment, error)
+}
+
+// BucketingSource is implemented by sources that know the user attribute each rollout of a flag buckets users by,
+// which ldapi.Rollout does not include.  rule is the index of a rule, or -1 for the fallthrough.  An empty attribute
+// means the rollout buckets users by key.
+type BucketingSource interface {
+	BucketBy(flagKey string, rule int) (string, error)
+}
+
+// OffVariationSource is implemented by sources that know whether a flag has an off variation, which
+// ldapi.FeatureFlagConfig cannot tell apart from variation 0.  ok is false if the flag has none, in which case an
+// sdk serves its default value.
+type OffVariationSource interface {
+	OffVariation(flagKey string) (variation int, ok bool, err error)
+}
+
+// Evaluator evaluates flags in a single environment
+type Evaluator struct {
+	env    string
+	source Source
+}
+
+// NewEvaluator creates an evaluator for an environment
+func NewEvaluator(env string, source Source) *Evaluator {
+	return &Evaluator{env: env, source: source}
+}
+
+// Evaluate returns the variation the user would receive
+func (e *Evaluator) Evaluate(flag ldapi.FeatureFlag, user User) (Result, error) {
+	return e.evaluate(flag, user, map[string]bool{})
+}
+
+func (e *Evaluator) evaluate(flag ldapi.FeatureFlag, user User, visited map[string]bool) (Result, error) {
+	if _, ok := user["key"]; !ok {
+		return errorResult(ErrorUserNotFound), nil
+	}
+	config, ok := flag.Environments[e.env]
+	if !ok {
+		return errorResult(ErrorFlagNotFound), fmt.Errorf(`flag "%s" has no environment "%s"`, flag.Key, e.env)
+	}
+
+	if !config.On {
+		return e.offResult(flag, config, Reason{Kind: ReasonOff})
+	}
+
+	visited[flag.Key] = true
+	defer delete(visited, flag.Key)
+	for _, prereq := range config.Prerequisites {
+		if visited[prereq.Key] {
+			return errorResult(ErrorMalformedFlag), fmt.Errorf(`prerequisite cycle at flag "%s"`, prereq.Key)
+		}
+		prereqFlag, err := e.source.GetFlag(prereq.Key)
+		if err != nil {
+			return errorResult(ErrorMalformedFlag), err
+		}
+		prereqResult, err := e.evaluate(*prereqFlag, user, visited)
+		if err != nil {
+			return prereqResult, err
+		}
+		prereqOn := prereqFlag.Environments[e.env].On
+		if !prereqOn || prereqResult.VariationIndex == nil || *prereqResult.VariationIndex != int(prereq.Variation) {
+			return e.offResult(flag, config, Reason{Kind: ReasonPrerequisiteFailed, PrerequisiteKey: prereq.Key})
+		}
+	}
+
+	userKey := fmt.Sprintf("%v", user["key"])
+	for _, target := range config.Targets {
+		for _, value := range target.Values {
+			if value == userKey {
+				return variationResult(flag, int(target.Variation), Reason{Kind: ReasonTargetMatch})
+			}
+		}
+	}
+
+	for i, rule := range config.Rules {
+		matched, err := e.ruleMatches(rule.Clauses, user)
+		if err != nil {
+			return errorResult(ErrorMalformedFlag), err
+		}
+		if matched {
+			index := i
+			reason := Reason{Kind: ReasonRuleMatch, RuleIndex: &index, RuleID: rule.Id}
+			bucketBy, err := e.bucketBy(flag.Key, i, rule.Rollout)
+			if err != nil {
+				return errorResult(ErrorMalformedFlag), err
+			}
+			return variationResult(flag, variationForUser(rule.Variation, rule.Rollout, user, flag.Key, bucketBy, config.Salt), reason)
+		}
+	}
+
+	if config.Fallthrough_ == nil {
+		return errorResult(ErrorMalformedFlag), errors.New("flag has no fallthrough")
+	}
+	bucketBy, err := e.bucketBy(flag.Key, -1, config.Fallthrough_.Rollout)
+	if err != nil {
+		return errorResult(ErrorMalformedFlag), err
+	}
+	variation := variationForUser(config.Fallthrough_.Variation, config.Fallthrough_.Rollout, user, flag.Key, bucketBy, config.Salt)
+	return variationResult(flag, variation, Reason{Kind: ReasonFallthrough})
+}
+
+// offResult returns the off variation of a flag, or no variation if the source knows the flag has none
+func (e *Evaluator) offResult(flag ldapi.FeatureFlag, config ldapi.FeatureFlagConfig, reason Reason) (Result, error) {
+	if source, ok := e.source.(OffVariationSource); ok {
+		index, ok, err := source.OffVariation(flag.Key)
+		if err != nil {
+			return errorResult(ErrorMalformedFlag), err
+		}
+		if !ok {
+			return Result{Reason: reason}, nil
+		}
+		return variationResult(flag, index, reason)
+	}
+	return variationResult(flag, int(config.OffVariation), reason)
+}
+
+// bucketBy returns the user attribute a rollout buckets users by, asking the source only when there is a rollout
+func (e *Evaluator) bucketBy(flagKey string, rule int, rollout *ldapi.Rollout) (string, error) {
+	if rollout == nil {
+		return "key", nil
+	}
+	if source, ok := e.source.(BucketingSource); ok {
+		attr, err := source.BucketBy(flagKey, rule)
+		if err != nil || attr != "" {
+			return attr, err
+		}
+	}
+	return "key", nil
+}
+
+func (e *Evaluator) ruleMatches(clauses []ldapi.Clause, user User) (bool, error) {
+	for _, clause := range clauses {
+		matched, err := e.clauseMatches(clause, user)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (e *Evaluator) clauseMatches(clause ldapi.Clause, user User) (bool, error) {
+	if clause.Op == OpSegmentMatch {
+		for _, v := range clause.Values {
+			segmentKey, ok := v.(string)
+			if !ok {
+				continue
+			}
+			segment, err := e.source.GetSegment(segmentKey)
+			if err != nil {
+				return false, err
+			}
+			if segmentContains(*segment, user) {
+				return !clause.Negate, nil
+			}
+		}
+		return clause.Negate, nil
+	}
+	return clauseMatchesUser(clause, user)
+}
+
+// segmentContains tests whether a user is in a segment.  The api does not expose the salt that the sdks use for
+// weighted segment rules, so weighted rules are bucketed with an empty salt.
+func segmentContains(segment ldapi.UserSegment, user User) bool {
+	userKey := fmt.Sprintf("%v", user["key"])
+	for _, k := range segment.Included {
+		if k == userKey {
+			return true
+		}
+	}
+	for _, k := range segment.Excluded {
+		if k == userKey {
+			return false
+		}
+	}
+	for _, rule := range segment.Rules {
+		matched := true
+		for _, clause := range rule.Clauses {
+			if m, err := clauseMatchesUser(clause, user); err != nil || !m {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		if rule.Weight == 0 {
+			return true
+		}
+		bucketBy := rule.BucketBy
+		if bucketBy == "" {
+			bucketBy = "key"
+		}
+		if bucketUser(user, segment.Key, bucketBy, "") < float64(rule.Weight)/100000.0 {
+			return true
+		}
+	}
+	return false
+}
+
+func variationForUser(variation int32, rollout *ldapi.Rollout, user User, key string, bucketBy string, salt string) int {
+	if rollout == nil {
+		return int(variation)
+	}
+	bucket := bucketUser(user, key, bucketBy, salt)
+	sum := 0.0
+	for _, wv := range rollout.Variations {
+		sum += float64(wv.Weight) / 100000.0
+		if bucket < sum {
+			return int(wv.Variation)
+		}
+	}
+	if len(rollout.Variations) == 0 {
+		return -1
+	}
+	return int(rollout.Variations[len(rollout.Variations)-1].Variation)
+}
+
+// bucketUser returns a number between 0 and 1 for the user that is stable for the key and salt
+func bucketUser(user User, key string, attr string, salt string) float64 {
+	value, ok := attributeValue(user, attr)
+	if !ok {
+		return 0
+	}
+	var idHash string
+	switch v := value.(type) {
+	case string:
+		idHash = v
+	case float64:
+		if v != float64(int64(v)) {
+			return 0
+		}
+		idHash = strconv.FormatInt(int64(v), 10)
+	default:
+		return 0
+	}
+	if secondary, ok := user["secondary"].(string); ok && secondary != "" {
+		idHash += "." + secondary
+	}
+	sum := sha1.Sum([]byte(key + "." + salt + "." + idHash)) // nolint:gosec // G401: sha1 is what the sdks use
+	hash := hex.EncodeToString(sum[:])[:15]
+	intValue, _ := strconv.ParseInt(hash, 16, 64)
+	return float64(intValue) / longScale
+}
+
+func variationResult(flag ldapi.FeatureFlag, index int, reason Reason) (Result, error) {
+	if index < 0 || index >= len(flag.Variations) {
+		return errorResult(ErrorMalformedFlag), fmt.Errorf("variation %d does not exist", index)
+	}
+	var value interface{}
+	if flag.Variations[index].Value != nil {
+		value = *flag.Variations[index].Value
+	}
+	return Result{VariationIndex: &index, Value: value, Reason: reason}, nil
+}
+
+func errorResult(kind string) Result {
+	return Result{Reason: Reason{Kind: ReasonError, ErrorKind: kind}}
+}
@@ -0,0 +1,261 @@
+package eval
+
+import (
+	"errors"
+	"testing"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mapSource struct {
+	flags    map[string]ldapi.FeatureFlag
+	segments map[string]ldapi.UserSegment
+}
+
+func (s mapSource) GetFlag(key string) (*ldapi.FeatureFlag, error) {
+	f, ok := s.flags[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &f, nil
+}
+
+func (s mapSource) GetSegment(key string) (*ldapi.UserSegment, error) {
+	seg, ok := s.segments[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &seg, nil
+}
+
+func variations(values ...interface{}) (result []ldapi.Variation) {
+	for i := range values {
+		result = append(result, ldapi.Variation{Value: &values[i]})
+	}
+	return result
+}
+
+func TestBucketUser(t *testing.T) {
+	specs := []struct {
+		key      string
+		expected float64
+	}{
+		{"userKeyA", 0.42157587},
+		{"userKeyB", 0.6708485},
+		{"userKeyC", 0.10343106},
+	}
+	for _, tt := range specs {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.InEpsilon(t, tt.expected, bucketUser(User{"key": tt.key}, "hashKey", "key", "saltyA"), 0.0000001)
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	flag := ldapi.FeatureFlag{
+		Key:        "flag",
+		Variations: variations("a", "b", "c"),
+		Environments: map[string]ldapi.FeatureFlagConfig{
+			"production": {
+				On:           true,
+				Salt:         "salt",
+				OffVariation: 2,
+				Targets:      []ldapi.Target{{Values: []string{"targeted"}, Variation: 1}},
+				Rules: []ldapi.Rule{
+					{Id: "rule-email", Variation: 1, Clauses: []ldapi.Clause{{Attribute: "email", Op: OpEndsWith, Values: []interface{}{"@example.com"}}}},
+					{Id: "rule-segment", Variation: 2, Clauses: []ldapi.Clause{{Attribute: "key", Op: OpSegmentMatch, Values: []interface{}{"beta"}}}},
+					{Id: "rule-custom", Variation: 1, Clauses: []ldapi.Clause{{Attribute: "plan", Op: OpIn, Values: []interface{}{"gold"}, Negate: true}}},
+				},
+				Fallthrough_: &ldapi.ModelFallthrough{Variation: 0},
+			},
+			"test": {On: false, OffVariation: 2},
+		},
+	}
+	source := mapSource{segments: map[string]ldapi.UserSegment{"beta": {Key: "beta", Included: []string{"beta-user"}}}}
+	one, two := 1, 2
+
+	specs := []struct {
+		name     string
+		env      string
+		user     User
+		expected int
+		reason   Reason
+	}{
+		{"off", "test", User{"key": "u"}, 2, Reason{Kind: ReasonOff}},
+		{"target", "production", User{"key": "targeted"}, 1, Reason{Kind: ReasonTargetMatch}},
+		{"rule", "production", User{"key": "u", "email": "u@example.com"}, 1,
+			Reason{Kind: ReasonRuleMatch, RuleIndex: intPtr(0), RuleID: "rule-email"}},
+		{"segment", "production", User{"key": "beta-user", "custom": map[string]interface{}{"plan": "gold"}}, 2,
+			Reason{Kind: ReasonRuleMatch, RuleIndex: &one, RuleID: "rule-segment"}},
+		{"negated custom attribute", "production", User{"key": "u", "custom": map[string]interface{}{"plan": "silver"}}, 1,
+			Reason{Kind: ReasonRuleMatch, RuleIndex: &two, RuleID: "rule-custom"}},
+		{"fallthrough", "production", User{"key": "u", "plan": "gold"}, 0, Reason{Kind: ReasonFallthrough}},
+	}
+	for _, tt := range specs {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewEvaluator(tt.env, source).Evaluate(flag, tt.user)
+			require.NoError(t, err)
+			require.NotNil(t, result.VariationIndex)
+			assert.Equal(t, tt.expected, *result.VariationIndex)
+			assert.Equal(t, tt.reason, result.Reason)
+		})
+	}
+}
+
+func TestPrerequisites(t *testing.T) {
+	prereq := ldapi.FeatureFlag{
+		Key:        "prereq",
+		Variations: variations(true, false),
+		Environments: map[string]ldapi.FeatureFlagConfig{
+			"production": {On: true, Fallthrough_: &ldapi.ModelFallthrough{Variation: 1}, OffVariation: 1},
+		},
+	}
+	flag := ldapi.FeatureFlag{
+		Key:        "flag",
+		Variations: variations(true, false),
+		Environments: map[string]ldapi.FeatureFlagConfig{
+			"production": {
+				On:            true,
+				Prerequisites: []ldapi.Prerequisite{{Key: "prereq", Variation: 0}},
+				Fallthrough_:  &ldapi.ModelFallthrough{Variation: 0},
+				OffVariation:  1,
+			},
+		},
+	}
+	source := mapSource{flags: map[string]ldapi.FeatureFlag{"prereq": prereq}}
+	result, err := NewEvaluator("production", source).Evaluate(flag, User{"key": "u"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, *result.VariationIndex)
+	assert.Equal(t, Reason{Kind: ReasonPrerequisiteFailed, PrerequisiteKey: "prereq"}, result.Reason)
+
+	prereq.Environments["production"] = ldapi.FeatureFlagConfig{On: true, Fallthrough_: &ldapi.ModelFallthrough{Variation: 0}}
+	result, err = NewEvaluator("production", source).Evaluate(flag, User{"key": "u"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, *result.VariationIndex)
+	assert.Equal(t, Reason{Kind: ReasonFallthrough}, result.Reason)
+}
+
+func TestRollout(t *testing.T) {
+	rollout := &ldapi.Rollout{Variations: []ldapi.WeightedVariation{{Variation: 0, Weight: 42157}, {Variation: 1, Weight: 57843}}}
+	assert.Equal(t, 1, variationForUser(0, rollout, User{"key": "userKeyA"}, "hashKey", "key", "saltyA"))
+	assert.Equal(t, 0, variationForUser(0, rollout, User{"key": "userKeyC"}, "hashKey", "key", "saltyA"))
+	assert.Equal(t, 1, variationForUser(0, rollout, User{"key": "userKeyC", "email": "userKeyA"}, "hashKey", "email", "saltyA"))
+}
+
+// bucketingSource buckets the rollouts of every flag by one attribute
+type bucketingSource struct {
+	mapSource
+	attr string
+}
+
+func (s bucketingSource) BucketBy(flagKey string, rule int) (string, error) {
+	return s.attr, nil
+}
+
+func TestRolloutBucketBy(t *testing.T) {
+	flag := ldapi.FeatureFlag{
+		Key:        "hashKey",
+		Variations: variations(false, true),
+		Environments: map[string]ldapi.FeatureFlagConfig{"test": {
+			On:   true,
+			Salt: "saltyA",
+			Fallthrough_: &ldapi.ModelFallthrough{Rollout: &ldapi.Rollout{Variations: []ldapi.WeightedVariation{
+				{Variation: 0, Weight: 42157}, {Variation: 1, Weight: 57843}}}},
+		}},
+	}
+	user := User{"key": "userKeyC", "email": "userKeyA"}
+
+	result, err := NewEvaluator("test", mapSource{}).Evaluate(flag, user)
+	require.NoError(t, err)
+	assert.Equal(t, 0, *result.VariationIndex)
+
+	result, err = NewEvaluator("test", bucketingSource{attr: "email"}).Evaluate(flag, user)
+	require.NoError(t, err)
+	assert.Equal(t, 1, *result.VariationIndex)
+}
+
+// offVariationSource knows which flags have no off variation
+type offVariationSource struct {
+	mapSource
+	none map[string]bool
+}
+
+func (s offVariationSource) OffVariation(flagKey string) (int, bool, error) {
+	if s.none[flagKey] {
+		return 0, false, nil
+	}
+	return 1, true, nil
+}
+
+func TestNullOffVariation(t *testing.T) {
+	flag := ldapi.FeatureFlag{
+		Key:          "flag",
+		Variations:   variations(true, false),
+		Environments: map[string]ldapi.FeatureFlagConfig{"test": {On: false}},
+	}
+
+	result, err := NewEvaluator("test", offVariationSource{none: map[string]bool{"flag": true}}).Evaluate(flag, User{"key": "u"})
+	require.NoError(t, err)
+	assert.Nil(t, result.VariationIndex)
+	assert.Nil(t, result.Value)
+	assert.Equal(t, Reason{Kind: ReasonOff}, result.Reason)
+
+	result, err = NewEvaluator("test", offVariationSource{}).Evaluate(flag, User{"key": "u"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, *result.VariationIndex)
+}
+
+func TestOperators(t *testing.T) {
+	specs := []struct {
+		op       string
+		user     interface{}
+		clause   interface{}
+		expected bool
+	}{
+		{OpIn, "a", "a", true},
+		{OpIn, 1.0, 1, true},
+		{OpIn, true, true, true},
+		{OpIn, map[string]interface{}{"a": 1.0}, map[string]interface{}{"a": 1.0}, false},
+		{OpIn, "a", map[string]interface{}{"a": 1.0}, false},
+		{OpStartsWith, "abc", "ab", true},
+		{OpContains, "abc", "d", false},
+		{OpMatches, "abc123", `^[a-z]+\d+$`, true},
+		{OpLessThan, 1.0, 2.0, true},
+		{OpGreaterThanOrEqual, 2.0, 2.0, true},
+		{OpLessThan, "1", 2.0, false},
+		{OpBefore, "2018-01-01T00:00:00Z", 1546300800000.0, true},
+		{OpAfter, 1546300800001.0, "2019-01-01T00:00:00Z", true},
+		{OpSemVerEqual, "2.0", "2.0.0", true},
+		{OpSemVerLessThan, "2.0.0-rc.1", "2.0.0", true},
+		{OpSemVerLessThan, "2.0.0-alpha", "2.0.0-alpha.1", true},
+		{OpSemVerGreaterThan, "2.10.0", "2.9.0", true},
+		{OpSemVerGreaterThan, "not.a.version", "1.0.0", false},
+	}
+	for _, tt := range specs {
+		t.Run(tt.op, func(t *testing.T) {
+			assert.Equal(t, tt.expected, operators[tt.op](tt.user, tt.clause))
+		})
+	}
+}
+
+func TestClauseWithObjects(t *testing.T) {
+	user := User{"key": "u", "custom": map[string]interface{}{
+		"address": map[string]interface{}{"city": "Oakland"},
+		"groups":  []interface{}{[]interface{}{"a"}, "b"},
+	}}
+	clause := ldapi.Clause{Attribute: "address", Op: OpIn, Values: []interface{}{map[string]interface{}{"city": "Oakland"}}}
+	matched, err := clauseMatchesUser(clause, user)
+	require.NoError(t, err)
+	assert.False(t, matched)
+
+	clause = ldapi.Clause{Attribute: "groups", Op: OpIn, Values: []interface{}{[]interface{}{"a"}, "b"}}
+	matched, err = clauseMatchesUser(clause, user)
+	require.NoError(t, err)
+	assert.True(t, matched)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
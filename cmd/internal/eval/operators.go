@@ -0,0 +1,259 @@
+package eval
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+)
+
+// Clause operators
+const (
+	OpIn                 = "in"
+	OpEndsWith           = "endsWith"
+	OpStartsWith         = "startsWith"
+	OpMatches            = "matches"
+	OpContains           = "contains"
+	OpLessThan           = "lessThan"
+	OpLessThanOrEqual    = "lessThanOrEqual"
+	OpGreaterThan        = "greaterThan"
+	OpGreaterThanOrEqual = "greaterThanOrEqual"
+	OpBefore             = "before"
+	OpAfter              = "after"
+	OpSegmentMatch       = "segmentMatch"
+	OpSemVerEqual        = "semVerEqual"
+	OpSemVerLessThan     = "semVerLessThan"
+	OpSemVerGreaterThan  = "semVerGreaterThan"
+)
+
+type operator func(userValue, clauseValue interface{}) bool
+
+var operators = map[string]operator{
+	OpIn: func(u, c interface{}) bool {
+		if un, ok := toNumber(u); ok {
+			cn, ok := toNumber(c)
+			return ok && un == cn
+		}
+		switch u.(type) {
+		case string, bool:
+			return u == c
+		}
+		// objects are not comparable, so they never match
+		return false
+	},
+	OpEndsWith:   stringOperator(strings.HasSuffix),
+	OpStartsWith: stringOperator(strings.HasPrefix),
+	OpContains:   stringOperator(strings.Contains),
+	OpMatches: stringOperator(func(u, c string) bool {
+		matched, err := regexp.MatchString(c, u)
+		return err == nil && matched
+	}),
+	OpLessThan:           numberOperator(func(u, c float64) bool { return u < c }),
+	OpLessThanOrEqual:    numberOperator(func(u, c float64) bool { return u <= c }),
+	OpGreaterThan:        numberOperator(func(u, c float64) bool { return u > c }),
+	OpGreaterThanOrEqual: numberOperator(func(u, c float64) bool { return u >= c }),
+	OpBefore:             timeOperator(func(u, c time.Time) bool { return u.Before(c) }),
+	OpAfter:              timeOperator(func(u, c time.Time) bool { return u.After(c) }),
+	OpSemVerEqual:        semVerOperator(func(cmp int) bool { return cmp == 0 }),
+	OpSemVerLessThan:     semVerOperator(func(cmp int) bool { return cmp < 0 }),
+	OpSemVerGreaterThan:  semVerOperator(func(cmp int) bool { return cmp > 0 }),
+}
+
+// builtInAttributes are the user attributes that are not custom
+var builtInAttributes = []string{"key", "secondary", "ip", "country", "email", "firstName", "lastName", "avatar", "name", "anonymous"}
+
+func attributeValue(user User, attr string) (interface{}, bool) {
+	if value, ok := user[attr]; ok {
+		return value, true
+	}
+	for _, a := range builtInAttributes {
+		if a == attr {
+			return nil, false
+		}
+	}
+	custom, ok := user["custom"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	value, ok := custom[attr]
+	return value, ok
+}
+
+// clauseMatchesUser tests a clause that does not refer to segments
+func clauseMatchesUser(clause ldapi.Clause, user User) (bool, error) {
+	op, ok := operators[clause.Op]
+	if !ok {
+		return false, fmt.Errorf(`unknown operator "%s"`, clause.Op)
+	}
+	value, ok := attributeValue(user, clause.Attribute)
+	if !ok || value == nil {
+		return false, nil
+	}
+	values, isList := value.([]interface{})
+	if !isList {
+		values = []interface{}{value}
+	}
+	for _, v := range values {
+		for _, cv := range clause.Values {
+			if op(v, cv) {
+				return !clause.Negate, nil
+			}
+		}
+	}
+	return clause.Negate, nil
+}
+
+func stringOperator(fn func(u, c string) bool) operator {
+	return func(u, c interface{}) bool {
+		us, ok := u.(string)
+		if !ok {
+			return false
+		}
+		cs, ok := c.(string)
+		return ok && fn(us, cs)
+	}
+}
+
+func numberOperator(fn func(u, c float64) bool) operator {
+	return func(u, c interface{}) bool {
+		un, ok := toNumber(u)
+		if !ok {
+			return false
+		}
+		cn, ok := toNumber(c)
+		return ok && fn(un, cn)
+	}
+}
+
+func timeOperator(fn func(u, c time.Time) bool) operator {
+	return func(u, c interface{}) bool {
+		ut, ok := toTime(u)
+		if !ok {
+			return false
+		}
+		ct, ok := toTime(c)
+		return ok && fn(ut, ct)
+	}
+}
+
+func semVerOperator(fn func(cmp int) bool) operator {
+	return func(u, c interface{}) bool {
+		us, ok := u.(string)
+		if !ok {
+			return false
+		}
+		cs, ok := c.(string)
+		if !ok {
+			return false
+		}
+		uv, err := parseSemVer(us)
+		if err != nil {
+			return false
+		}
+		cv, err := parseSemVer(cs)
+		if err != nil {
+			return false
+		}
+		return fn(uv.compare(cv))
+	}
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// toTime accepts unix milliseconds or RFC3339 timestamps
+func toTime(v interface{}) (time.Time, bool) {
+	if n, ok := toNumber(v); ok {
+		return time.Unix(0, int64(n)*int64(time.Millisecond)), true
+	}
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	return t, err == nil
+}
+
+type semVer struct {
+	major, minor, patch int
+	prerelease          []string
+}
+
+var semVerRegex = regexp.MustCompile(`^(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// parseSemVer parses a semantic version, allowing the minor and patch versions to be omitted as the sdks do
+func parseSemVer(s string) (semVer, error) {
+	m := semVerRegex.FindStringSubmatch(s)
+	if m == nil {
+		return semVer{}, fmt.Errorf(`invalid semantic version "%s"`, s)
+	}
+	var v semVer
+	v.major, _ = strconv.Atoi(m[1])
+	if m[2] != "" {
+		v.minor, _ = strconv.Atoi(m[2])
+	}
+	if m[3] != "" {
+		v.patch, _ = strconv.Atoi(m[3])
+	}
+	if m[4] != "" {
+		v.prerelease = strings.Split(m[4], ".")
+	}
+	return v, nil
+}
+
+func (v semVer) compare(o semVer) int {
+	for _, d := range []int{v.major - o.major, v.minor - o.minor, v.patch - o.patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	// a version without a prerelease has higher precedence
+	switch {
+	case len(v.prerelease) == 0 && len(o.prerelease) == 0:
+		return 0
+	case len(v.prerelease) == 0:
+		return 1
+	case len(o.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.prerelease) && i < len(o.prerelease); i++ {
+		a, b := v.prerelease[i], o.prerelease[i]
+		an, aErr := strconv.Atoi(a)
+		bn, bErr := strconv.Atoi(b)
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		case a != b:
+			return strings.Compare(a, b)
+		}
+	}
+	return sign(len(v.prerelease) - len(o.prerelease))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
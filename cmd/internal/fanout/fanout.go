@@ -0,0 +1,89 @@
+// Package fanout runs a read for several configs at once, keeping the results in the order of the configs
+package fanout
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Result is the result of a read for one config
+type Result struct {
+	Config string
+	Value  interface{}
+	Err    error
+}
+
+// Run calls read for each config, running at most limit reads at once.  The results are in the same order as the
+// configs, and a failure for one config does not stop the others.
+func Run(configs []string, limit int, read func(config string) (interface{}, error)) []Result {
+	if limit < 1 {
+		limit = 1
+	}
+	results := make([]Result, len(configs))
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, config := range configs {
+		wg.Add(1)
+		go func(i int, config string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			value, err := read(config)
+			results[i] = Result{Config: config, Value: value, Err: err}
+		}(i, config)
+	}
+	wg.Wait()
+	return results
+}
+
+// Select returns the configs to read, sorted by name: every one if all is set, or the requested ones, which must
+// exist
+func Select(available []string, requested []string, all bool) ([]string, error) {
+	var selected []string
+	if all {
+		selected = append(selected, available...)
+	} else {
+		var unknown []string
+		for _, config := range requested {
+			if !contains(available, config) {
+				unknown = append(unknown, config)
+			} else if !contains(selected, config) {
+				selected = append(selected, config)
+			}
+		}
+		if len(unknown) > 0 {
+			return nil, fmt.Errorf("unknown configs: %s", strings.Join(unknown, ", "))
+		}
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("no configs to read")
+	}
+	sort.Strings(selected)
+	return selected, nil
+}
+
+// Errors returns an error describing the configs that failed, or nil if none did
+func Errors(results []Result) error {
+	var failures []string
+	for _, r := range results {
+		if r.Err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", r.Config, r.Err))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("unable to read %d of %d configs: %s", len(failures), len(results), strings.Join(failures, "; "))
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
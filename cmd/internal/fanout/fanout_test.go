@@ -0,0 +1,56 @@
+package fanout
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	var running, most int32
+	results := Run([]string{"a", "b", "c", "d"}, 2, func(config string) (interface{}, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&most)
+			if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		if config == "c" {
+			return nil, errors.New("failed")
+		}
+		return config + "!", nil
+	})
+	require.Len(t, results, 4)
+	assert.Equal(t, Result{Config: "a", Value: "a!"}, results[0])
+	assert.Equal(t, "d!", results[3].Value)
+	assert.EqualError(t, results[2].Err, "failed")
+	assert.True(t, most <= 2, "at most 2 at once, got %d", most)
+
+	assert.EqualError(t, Errors(results), "unable to read 1 of 4 configs: c: failed")
+	assert.NoError(t, Errors(results[:2]))
+}
+
+func TestSelect(t *testing.T) {
+	available := []string{"prod", "dev", "staging"}
+
+	configs, err := Select(available, nil, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev", "prod", "staging"}, configs)
+
+	configs, err = Select(available, []string{"staging", "dev", "dev"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev", "staging"}, configs)
+
+	_, err = Select(available, []string{"dev", "qa"}, false)
+	assert.EqualError(t, err, "unknown configs: qa")
+
+	_, err = Select(nil, nil, true)
+	assert.Error(t, err)
+}
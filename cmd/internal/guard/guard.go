@@ -0,0 +1,255 @@
+// Package guard works out what a patch to a flag changes, so the change can be checked against guardrails before it
+// is sent
+package guard
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+)
+
+// totalWeight is the sum of the weights of a rollout, which are in thousandths of a percent
+const totalWeight = 100000
+
+// Environments returns the keys of the environments changed by a patch to a flag, in the order they are first changed
+func Environments(ops []ldapi.PatchOperation) []string {
+	var envs []string
+	seen := make(map[string]bool)
+	for _, op := range ops {
+		if op.Op == "test" {
+			continue
+		}
+		tokens := strings.Split(strings.TrimPrefix(op.Path, "/"), "/")
+		if len(tokens) < 2 || tokens[0] != "environments" {
+			continue
+		}
+		env := unescape(tokens[1])
+		if !seen[env] {
+			seen[env] = true
+			envs = append(envs, env)
+		}
+	}
+	return envs
+}
+
+// Apply returns a copy of a decoded json document with patch operations applied.  Test operations are ignored.
+func Apply(doc interface{}, ops []ldapi.PatchOperation) (interface{}, error) {
+	doc, err := normalize(doc)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		var value interface{}
+		if op.Value != nil {
+			if value, err = normalize(*op.Value); err != nil {
+				return nil, err
+			}
+		}
+		switch op.Op {
+		case "test":
+			continue
+		case "add", "replace", "remove":
+			doc, err = apply(doc, op.Op, op.Path, value)
+		default:
+			err = fmt.Errorf("unsupported patch operation %s", op.Op)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// normalize copies a value as decoded json, so it only contains maps, slices and basic types
+func normalize(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+func apply(doc interface{}, op string, pointer string, value interface{}) (interface{}, error) {
+	if pointer == "" {
+		if op == "remove" {
+			return nil, nil
+		}
+		return value, nil
+	}
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	last := tokens[len(tokens)-1]
+	parent := doc
+	for _, token := range tokens[:len(tokens)-1] {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			parent = p[unescape(token)]
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(p) {
+				return nil, fmt.Errorf("%s does not exist", pointer)
+			}
+			parent = p[i]
+		default:
+			return nil, fmt.Errorf("%s does not exist", pointer)
+		}
+	}
+
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		// like the api, replacing or removing a missing property is not an error
+		key := unescape(last)
+		if op == "remove" {
+			delete(p, key)
+		} else {
+			p[key] = value
+		}
+		return doc, nil
+	case []interface{}:
+		i := len(p)
+		if last != "-" || op != "add" {
+			var err error
+			if i, err = strconv.Atoi(last); err != nil || i < 0 || i > len(p) || (i == len(p) && op != "add") {
+				return nil, fmt.Errorf("%s does not exist", pointer)
+			}
+		}
+		var updated []interface{}
+		switch op {
+		case "add":
+			updated = append(append(append([]interface{}{}, p[:i]...), value), p[i:]...)
+		case "remove":
+			updated = append(append([]interface{}{}, p[:i]...), p[i+1:]...)
+		default:
+			updated = append([]interface{}{}, p...)
+			updated[i] = value
+		}
+		// arrays are replaced rather than modified, so the new array is set on its parent
+		parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+		return apply(doc, "replace", parentPointer, updated)
+	}
+	return nil, fmt.Errorf("%s does not exist", pointer)
+}
+
+func unescape(token string) string {
+	return strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+}
+
+// Step is a change to what the fallthrough or a rule of a flag serves
+type Step struct {
+	Environment string
+	// Target is "fallthrough" or the _id of a rule, or its index if it has no _id
+	Target string
+	// Weight is the largest change to the share of users served one variation, in thousandths of a percent
+	Weight int
+}
+
+// Percent returns the size of the step as a percentage of users
+func (s Step) Percent() float64 {
+	return float64(s.Weight) / 1000
+}
+
+// Steps compares what the fallthrough and rules of a decoded flag serve before and after a change, returning a step
+// for each one that changed.  Rules that were added or removed are not included.
+func Steps(before, after interface{}) []Step {
+	var steps []Step
+	beforeEnvs := lookupMap(before, "environments")
+	afterEnvs := lookupMap(after, "environments")
+	envKeys := make([]string, 0, len(afterEnvs))
+	for env := range afterEnvs {
+		envKeys = append(envKeys, env)
+	}
+	sort.Strings(envKeys)
+
+	for _, env := range envKeys {
+		beforeEnv, ok := beforeEnvs[env].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		afterEnv, _ := afterEnvs[env].(map[string]interface{})
+		add := func(target string, b, a interface{}) {
+			if weight := largestChange(served(b), served(a)); weight > 0 {
+				steps = append(steps, Step{Environment: env, Target: target, Weight: weight})
+			}
+		}
+		add("fallthrough", beforeEnv["fallthrough"], afterEnv["fallthrough"])
+
+		beforeRules := rulesByID(beforeEnv["rules"])
+		for id, rule := range rulesByID(afterEnv["rules"]) {
+			if beforeRule, ok := beforeRules[id]; ok {
+				add(id, beforeRule, rule)
+			}
+		}
+	}
+	sort.SliceStable(steps, func(i, j int) bool {
+		if steps[i].Environment != steps[j].Environment {
+			return steps[i].Environment < steps[j].Environment
+		}
+		return steps[i].Target < steps[j].Target
+	})
+	return steps
+}
+
+func lookupMap(doc interface{}, key string) map[string]interface{} {
+	m, _ := doc.(map[string]interface{})
+	result, _ := m[key].(map[string]interface{})
+	return result
+}
+
+func rulesByID(rules interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	list, _ := rules.([]interface{})
+	for i, rule := range list {
+		id := strconv.Itoa(i)
+		if m, ok := rule.(map[string]interface{}); ok {
+			if ruleID, ok := m["_id"].(string); ok && ruleID != "" {
+				id = ruleID
+			}
+		}
+		result[id] = rule
+	}
+	return result
+}
+
+// served returns the share of users served each variation by a fallthrough or rule
+func served(target interface{}) map[int]int {
+	result := make(map[int]int)
+	m, ok := target.(map[string]interface{})
+	if !ok {
+		return result
+	}
+	if rollout, ok := m["rollout"].(map[string]interface{}); ok {
+		variations, _ := rollout["variations"].([]interface{})
+		for _, v := range variations {
+			wv, _ := v.(map[string]interface{})
+			variation, _ := wv["variation"].(float64)
+			weight, _ := wv["weight"].(float64)
+			result[int(variation)] += int(weight)
+		}
+		return result
+	}
+	if variation, ok := m["variation"].(float64); ok {
+		result[int(variation)] = totalWeight
+	}
+	return result
+}
+
+func largestChange(before, after map[int]int) int {
+	largest := 0
+	for _, shares := range []map[int]int{before, after} {
+		for variation := range shares {
+			change := after[variation] - before[variation]
+			if change < 0 {
+				change = -change
+			}
+			if change > largest {
+				largest = change
+			}
+		}
+	}
+	return largest
+}
@@ -0,0 +1,87 @@
+package guard
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	var v interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &v))
+	return v
+}
+
+func op(o, path string, value interface{}) ldapi.PatchOperation {
+	if value == nil {
+		return ldapi.PatchOperation{Op: o, Path: path}
+	}
+	return ldapi.PatchOperation{Op: o, Path: path, Value: &value}
+}
+
+func TestEnvironments(t *testing.T) {
+	envs := Environments([]ldapi.PatchOperation{
+		op("test", "/_version", 1),
+		op("replace", "/environments/production/on", true),
+		op("replace", "/name", "x"),
+		op("add", "/environments/a~1b/rules/-", map[string]interface{}{}),
+		op("remove", "/environments/production/fallthrough/variation", nil),
+	})
+	assert.Equal(t, []string{"production", "a/b"}, envs)
+}
+
+func TestApply(t *testing.T) {
+	specs := []struct {
+		name     string
+		doc      string
+		ops      []ldapi.PatchOperation
+		expected string
+	}{
+		{"replace", `{"a": 1}`, []ldapi.PatchOperation{op("test", "/a", 2), op("replace", "/a", 2)}, `{"a": 2}`},
+		{"replace missing", `{"f": {"rollout": {}}}`,
+			[]ldapi.PatchOperation{op("remove", "/f/rollout", nil), op("replace", "/f/variation", 1)}, `{"f": {"variation": 1}}`},
+		{"add and remove", `{"f": {"variation": 1}}`,
+			[]ldapi.PatchOperation{op("remove", "/f/variation", nil), op("add", "/f/rollout", map[string]int{"w": 1})},
+			`{"f": {"rollout": {"w": 1}}}`},
+		{"array", `{"t": ["a", "c"]}`,
+			[]ldapi.PatchOperation{op("add", "/t/1", "b"), op("add", "/t/-", "d"), op("remove", "/t/0", nil), op("replace", "/t/0", "x")},
+			`{"t": ["x", "c", "d"]}`},
+	}
+	for _, tt := range specs {
+		t.Run(tt.name, func(t *testing.T) {
+			original := decode(t, tt.doc)
+			result, err := Apply(original, tt.ops)
+			require.NoError(t, err)
+			assert.Equal(t, decode(t, tt.expected), result)
+			assert.Equal(t, decode(t, tt.doc), original, "original is unchanged")
+		})
+	}
+
+	_, err := Apply(decode(t, `{"a": 1}`), []ldapi.PatchOperation{op("replace", "/b/c", 1)})
+	assert.Error(t, err)
+	_, err = Apply(decode(t, `{"t": []}`), []ldapi.PatchOperation{op("remove", "/t/0", nil)})
+	assert.Error(t, err)
+}
+
+func TestSteps(t *testing.T) {
+	before := decode(t, `{"environments": {
+		"production": {"fallthrough": {"variation": 0}, "rules": [{"_id": "r1", "variation": 1}, {"_id": "r2", "variation": 1}]},
+		"test": {"fallthrough": {"rollout": {"variations": [{"variation": 0, "weight": 90000}, {"variation": 1, "weight": 10000}]}}}
+	}}`)
+	after := decode(t, `{"environments": {
+		"production": {"fallthrough": {"rollout": {"variations": [{"variation": 0, "weight": 75000}, {"variation": 1, "weight": 25000}]}},
+			"rules": [{"_id": "r2", "variation": 0}, {"_id": "r3", "variation": 0}]},
+		"test": {"fallthrough": {"rollout": {"variations": [{"variation": 0, "weight": 90000}, {"variation": 1, "weight": 10000}]}}},
+		"new": {"fallthrough": {"variation": 1}}
+	}}`)
+	steps := Steps(before, after)
+	assert.Equal(t, []Step{
+		{Environment: "production", Target: "fallthrough", Weight: 25000},
+		{Environment: "production", Target: "r2", Weight: 100000},
+	}, steps)
+	assert.Equal(t, 25.0, steps[0].Percent())
+}
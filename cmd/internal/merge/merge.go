@@ -0,0 +1,224 @@
+// Package merge performs three-way merges of json documents
+package merge
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Markers delimit the two sides of a conflict in marked up documents
+const (
+	MarkerMine   = "<<<<<<< mine"
+	MarkerSep    = "======="
+	MarkerTheirs = ">>>>>>> theirs"
+)
+
+type missingValue struct{}
+
+// missing stands in for a value that is not present in a document
+var missing = &missingValue{}
+
+// Conflict is a value that was changed differently in both documents
+type Conflict struct {
+	// Path is the json pointer to the value
+	Path   string
+	Base   interface{}
+	Mine   interface{}
+	Theirs interface{}
+}
+
+// Merge combines the changes made to base in mine and theirs, which are decoded json values.  Conflicting values are
+// taken from mine and reported as conflicts.
+func Merge(base, mine, theirs interface{}) (interface{}, []Conflict) {
+	var conflicts []Conflict
+	merged := merge("", base, mine, theirs, &conflicts)
+	if merged == missing {
+		merged = nil
+	}
+	return merged, conflicts
+}
+
+func merge(path string, base, mine, theirs interface{}, conflicts *[]Conflict) interface{} {
+	switch {
+	case reflect.DeepEqual(mine, theirs):
+		return mine
+	case reflect.DeepEqual(base, mine):
+		return theirs
+	case reflect.DeepEqual(base, theirs):
+		return mine
+	}
+
+	bm, bok := base.(map[string]interface{})
+	mm, mok := mine.(map[string]interface{})
+	tm, tok := theirs.(map[string]interface{})
+	if mok && tok {
+		if !bok {
+			bm = map[string]interface{}{}
+		}
+		keys := map[string]bool{}
+		for _, m := range []map[string]interface{}{bm, mm, tm} {
+			for k := range m {
+				keys[k] = true
+			}
+		}
+		result := make(map[string]interface{})
+		for _, k := range sortedKeys(keys) {
+			v := merge(path+"/"+escape(k), lookup(bm, k), lookup(mm, k), lookup(tm, k), conflicts)
+			if v != missing {
+				result[k] = v
+			}
+		}
+		return result
+	}
+
+	ba, bok := base.([]interface{})
+	ma, mok := mine.([]interface{})
+	ta, tok := theirs.([]interface{})
+	if bok && mok && tok && len(ba) == len(ma) && len(ba) == len(ta) {
+		result := make([]interface{}, len(ba))
+		for i := range ba {
+			result[i] = merge(path+"/"+strconv.Itoa(i), ba[i], ma[i], ta[i], conflicts)
+		}
+		return result
+	}
+
+	*conflicts = append(*conflicts, Conflict{Path: path, Base: base, Mine: mine, Theirs: theirs})
+	return mine
+}
+
+func lookup(m map[string]interface{}, key string) interface{} {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	return missing
+}
+
+func sortedKeys(keys map[string]bool) []string {
+	result := make([]string, 0, len(keys))
+	for k := range keys {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func escape(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
+
+func unescape(token string) string {
+	return strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+}
+
+func placeholder(i int) string {
+	return fmt.Sprintf("__ldc_conflict_%d__", i)
+}
+
+var placeholderRegex = regexp.MustCompile(`"__ldc_conflict_(\d+)__"`)
+
+// Markup renders a merged document as indented json with each conflict surrounded by markers showing both sides
+func Markup(merged interface{}, conflicts []Conflict, indent string) ([]byte, error) {
+	// copy the document so placeholders do not modify the inputs of the merge
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	for i, c := range conflicts {
+		doc = setAt(doc, c.Path, placeholder(i))
+	}
+	data, err = json.MarshalIndent(doc, "", indent)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	for _, line := range strings.Split(string(data), "\n") {
+		loc := placeholderRegex.FindStringSubmatchIndex(line)
+		if loc == nil {
+			out = append(out, line)
+			continue
+		}
+		i, _ := strconv.Atoi(line[loc[2]:loc[3]])
+		prefix, suffix := line[:loc[0]], line[loc[1]:]
+		lineIndent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		out = append(out, MarkerMine)
+		mine, err := renderSide(prefix, conflicts[i].Mine, suffix, lineIndent, indent)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, mine...)
+		out = append(out, MarkerSep)
+		theirs, err := renderSide(prefix, conflicts[i].Theirs, suffix, lineIndent, indent)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, theirs...)
+		out = append(out, MarkerTheirs)
+	}
+	return []byte(strings.Join(out, "\n")), nil
+}
+
+func renderSide(prefix string, value interface{}, suffix string, lineIndent string, indent string) ([]string, error) {
+	if value == missing {
+		return nil, nil
+	}
+	data, err := json.MarshalIndent(value, lineIndent, indent)
+	if err != nil {
+		return nil, err
+	}
+	return []string{prefix + string(data) + suffix}, nil
+}
+
+// setAt replaces the value at a json pointer, returning the updated document
+func setAt(doc interface{}, pointer string, value interface{}) interface{} {
+	if pointer == "" {
+		return value
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	parent := doc
+	for i, token := range tokens {
+		last := i == len(tokens)-1
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			key := unescape(token)
+			if last {
+				p[key] = value
+				return doc
+			}
+			parent = p[key]
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index >= len(p) {
+				return doc
+			}
+			if last {
+				p[index] = value
+				return doc
+			}
+			parent = p[index]
+		default:
+			return doc
+		}
+	}
+	return doc
+}
+
+// HasMarkers returns true if a document still contains conflict markers
+func HasMarkers(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, MarkerMine) || line == MarkerSep || strings.HasPrefix(line, MarkerTheirs) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package merge
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	var v interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &v))
+	return v
+}
+
+func TestMerge(t *testing.T) {
+	specs := []struct {
+		name      string
+		base      string
+		mine      string
+		theirs    string
+		expected  string
+		conflicts []string
+	}{
+		{"only mine", `{"a": 1, "b": 2}`, `{"a": 3, "b": 2}`, `{"a": 1, "b": 2}`, `{"a": 3, "b": 2}`, nil},
+		{"only theirs", `{"a": 1}`, `{"a": 1}`, `{"a": 2}`, `{"a": 2}`, nil},
+		{"different keys", `{"a": 1, "b": 2}`, `{"a": 3, "b": 2}`, `{"a": 1, "b": 4}`, `{"a": 3, "b": 4}`, nil},
+		{"nested", `{"e": {"on": false, "salt": "x"}}`, `{"e": {"on": true, "salt": "x"}}`, `{"e": {"on": false, "salt": "y"}}`,
+			`{"e": {"on": true, "salt": "y"}}`, nil},
+		{"added and removed", `{"a": 1, "b": 2}`, `{"a": 1, "b": 2, "c": 3}`, `{"a": 1}`, `{"a": 1, "c": 3}`, nil},
+		{"same change", `{"a": 1}`, `{"a": 2}`, `{"a": 2}`, `{"a": 2}`, nil},
+		{"array elements", `{"t": ["a", "b"]}`, `{"t": ["c", "b"]}`, `{"t": ["a", "d"]}`, `{"t": ["c", "d"]}`, nil},
+		{"conflict", `{"a": 1, "b": 1}`, `{"a": 2, "b": 1}`, `{"a": 3, "b": 2}`, `{"a": 2, "b": 2}`, []string{"/a"}},
+		{"array length conflict", `{"t": ["a"]}`, `{"t": ["a", "b"]}`, `{"t": []}`, `{"t": ["a", "b"]}`, []string{"/t"}},
+		{"deleted and changed", `{"a/b": 1}`, `{}`, `{"a/b": 2}`, `{}`, []string{"/a~1b"}},
+	}
+	for _, tt := range specs {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflicts := Merge(decode(t, tt.base), decode(t, tt.mine), decode(t, tt.theirs))
+			assert.Equal(t, decode(t, tt.expected), merged)
+			var paths []string
+			for _, c := range conflicts {
+				paths = append(paths, c.Path)
+			}
+			assert.Equal(t, tt.conflicts, paths)
+		})
+	}
+}
+
+func TestMarkup(t *testing.T) {
+	merged, conflicts := Merge(decode(t, `{"a": 1, "b": {"c": 1}, "d": 1}`), decode(t, `{"a": 2, "b": {"c": 2}}`),
+		decode(t, `{"a": 3, "b": {"c": 3}, "d": 2}`))
+	require.Len(t, conflicts, 3)
+	data, err := Markup(merged, conflicts, "  ")
+	require.NoError(t, err)
+	assert.Equal(t, `{
+<<<<<<< mine
+  "a": 2,
+=======
+  "a": 3,
+>>>>>>> theirs
+  "b": {
+<<<<<<< mine
+    "c": 2
+=======
+    "c": 3
+>>>>>>> theirs
+  },
+<<<<<<< mine
+=======
+  "d": 2
+>>>>>>> theirs
+}`, string(data))
+	assert.True(t, HasMarkers(data))
+	assert.False(t, HasMarkers([]byte(`{"a": "======="}`)))
+}
@@ -2,6 +2,7 @@ package path
 
 import (
 	"sort"
+	"strings"
 )
 
 // Lister is an interface that returns a list of children for a parent path
@@ -91,7 +92,7 @@ func (c *Completer) GetCompletions(arg string) ([]string, error) {
 
 		if trueParentDepth < len(c.listers) && len(c.listers) <= defaultPath.Depth() {
 			parentIsDefault := true
-			for _, p := range parentPath.Keys() {
+			for _, p := range parentPath.Keys()[:trueParentDepth] {
 				if p != "..." {
 					parentIsDefault = false
 				}
@@ -101,7 +102,8 @@ func (c *Completer) GetCompletions(arg string) ([]string, error) {
 			}
 		}
 		if originalParentPath.Depth() >= 1 {
-			prefix = originalParentPath.String() + "/"
+			// the parent of a path in a config with no keys, as in "//config/", already ends in a slash
+			prefix = strings.TrimSuffix(originalParentPath.String(), "/") + "/"
 		} else {
 			prefix = "/"
 		}
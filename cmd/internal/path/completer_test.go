@@ -99,6 +99,22 @@ var _ = Describe("GetCompletions", func() {
 			Expect(completions).To(BeEquivalentTo([]string{"/...", "//", "/projA"}))
 		})
 
+		It("completes projects in a config", func() {
+			defaultPathSource = path.DefaultPathSourceFunc(func(configKey *string) (path.ResourcePath, error) {
+				Expect(configKey).To(BeEquivalentTo(strPtr("configA")))
+				return path.ResourcePath("/defaultProj/defaultEnv"), nil
+			})
+
+			completer := path.NewCompleter(defaultPathSource, nil,
+				path.ListerFunc(func(parentPath path.ResourcePath) ([]string, error) {
+					Expect(parentPath).To(BeEquivalentTo("//configA/"))
+					return []string{"projA"}, nil
+				}),
+				nil)
+			completions, _ := completer.GetCompletions("//configA/")
+			Expect(completions).To(BeEquivalentTo([]string{"//configA/.../", "//configA/projA/"}))
+		})
+
 		It("completes configs", func() {
 			completer := path.NewCompleter(defaultPathSource,
 				path.ListerFunc(func(parentPath path.ResourcePath) ([]string, error) {
@@ -0,0 +1,239 @@
+// Package ramp plans progressive rollouts and keeps track of their progress in state files
+package ramp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+)
+
+// Ramp statuses
+const (
+	StatusRunning    = "running"
+	StatusPaused     = "paused"
+	StatusCompleted  = "completed"
+	StatusAborted    = "aborted"
+	StatusRolledBack = "rolled-back"
+)
+
+// What to do when someone else changes a flag during a ramp
+const (
+	OnConflictPause    = "pause"
+	OnConflictRollback = "rollback"
+)
+
+// lockWait is how long Lock waits for another process to release a state file
+const lockWait = 30 * time.Second
+
+// lockRetryInterval is how often Lock tries again to take a lock held by another process
+const lockRetryInterval = 100 * time.Millisecond
+
+// staleLockAge is the age after which a lock is assumed to have been left by a process that died while holding it
+const staleLockAge = 2 * time.Minute
+
+// totalWeight is the sum of the weights of a rollout
+const totalWeight = 100000
+
+// State is the progress of a ramp
+type State struct {
+	// Path is the path of the flag configuration being ramped
+	Path string `json:"path"`
+	// To is the index of the variation being rolled out
+	To int `json:"to"`
+	// Steps are the percentages of users that receive the variation at each step
+	Steps []float64 `json:"steps"`
+	// Interval is the time between steps
+	Interval string `json:"interval"`
+	// OnConflict is what to do when someone else changes the flag
+	OnConflict string `json:"onConflict"`
+	// Comment is added to the comment recorded with each change
+	Comment string `json:"comment,omitempty"`
+	// Step is the number of steps that have been applied
+	Step int `json:"step"`
+	// Version is the version of the flag after the last change made by the ramp
+	Version int32 `json:"version"`
+	// Original is the fallthrough before the ramp started, used for rollbacks
+	Original ldapi.ModelFallthrough `json:"original"`
+	// NextStepAt is when the next step is due
+	NextStepAt time.Time `json:"nextStepAt"`
+	Status     string    `json:"status"`
+	Reason     string    `json:"reason,omitempty"`
+	// PID is the process running the ramp
+	PID       int       `json:"pid,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Active returns true if the ramp has not finished
+func (s *State) Active() bool {
+	return s.Status == StatusRunning || s.Status == StatusPaused
+}
+
+// Percent returns the percentage of users currently receiving the variation
+func (s *State) Percent() float64 {
+	if s.Step == 0 {
+		return 0
+	}
+	return s.Steps[s.Step-1]
+}
+
+// ParseSteps parses a comma-separated list of increasing percentages
+func ParseSteps(s string) ([]float64, error) {
+	var steps []float64
+	for _, part := range strings.Split(s, ",") {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(part, "%")), 64)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid step "%s"`, part)
+		}
+		if percent <= 0 || percent > 100 {
+			return nil, fmt.Errorf("step %v must be greater than 0 and at most 100", percent)
+		}
+		if len(steps) > 0 && percent <= steps[len(steps)-1] {
+			return nil, errors.New("steps must be increasing")
+		}
+		steps = append(steps, percent)
+	}
+	return steps, nil
+}
+
+// Weights returns a rollout that serves variation to the given percentage of users.  The remaining users are split
+// between the other variations in the same proportions as the original fallthrough.
+func Weights(original ldapi.ModelFallthrough, variations int, to int, percent float64) ([]ldapi.WeightedVariation, error) {
+	if to < 0 || to >= variations {
+		return nil, fmt.Errorf("variation %d does not exist", to)
+	}
+	base := make([]int64, variations)
+	if original.Rollout != nil {
+		for _, wv := range original.Rollout.Variations {
+			if int(wv.Variation) < variations {
+				base[wv.Variation] += int64(wv.Weight)
+			}
+		}
+	} else if int(original.Variation) < variations {
+		base[original.Variation] = totalWeight
+	}
+
+	var others []int
+	var otherTotal int64
+	for i, w := range base {
+		if i != to && w > 0 {
+			others = append(others, i)
+			otherTotal += w
+		}
+	}
+	if otherTotal == 0 {
+		return nil, fmt.Errorf("all users already receive variation %d", to)
+	}
+
+	target := int64(math.Round(percent * totalWeight / 100))
+	weights := make([]int64, variations)
+	weights[to] = target
+	remaining := totalWeight - target
+	assigned := target
+	for _, i := range others {
+		weights[i] = base[i] * remaining / otherTotal
+		assigned += weights[i]
+	}
+	// give any rounding remainder to the variation with the largest share
+	sort.SliceStable(others, func(a, b int) bool { return base[others[a]] > base[others[b]] })
+	weights[others[0]] += totalWeight - assigned
+
+	result := make([]ldapi.WeightedVariation, variations)
+	for i, w := range weights {
+		result[i] = ldapi.WeightedVariation{Variation: int32(i), Weight: int32(w)}
+	}
+	return result, nil
+}
+
+// LargestStep returns the largest increase, in percent, in the share of users receiving variation to from one step
+// of a ramp to the next, starting from the original fallthrough
+func LargestStep(original ldapi.ModelFallthrough, to int, steps []float64) float64 {
+	var previous float64
+	if original.Rollout != nil {
+		for _, wv := range original.Rollout.Variations {
+			if int(wv.Variation) == to {
+				previous += float64(wv.Weight) * 100 / totalWeight
+			}
+		}
+	} else if int(original.Variation) == to {
+		previous = 100
+	}
+	var largest float64
+	for _, percent := range steps {
+		if percent-previous > largest {
+			largest = percent - previous
+		}
+		previous = percent
+	}
+	return largest
+}
+
+// Load reads a state file, returning nil if it does not exist
+func Load(filename string) (*State, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid ramp state in %s: %s", filename, err)
+	}
+	return &state, nil
+}
+
+// Save writes a state file, replacing it atomically so a running ramp never reads a partial file
+func (s *State) Save(filename string) error {
+	s.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// Lock takes an exclusive lock on a state file so that it cannot be changed by another process between being read
+// and saved.  It waits while another process holds the lock, and returns a function that releases it.
+func Lock(filename string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return nil, err
+	}
+	lockFile := filename + ".lock"
+	deadline := time.Now().Add(lockWait)
+	for {
+		f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(lockFile) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(lockFile); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			_ = os.Remove(lockFile)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for another process to unlock %s", filename)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
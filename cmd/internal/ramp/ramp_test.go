@@ -0,0 +1,118 @@
+package ramp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSteps(t *testing.T) {
+	steps, err := ParseSteps("1,5%, 25,50,100")
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1, 5, 25, 50, 100}, steps)
+
+	for _, invalid := range []string{"", "5,1", "0,50", "50,101", "a"} {
+		_, err := ParseSteps(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func weights(w ...int32) (result []ldapi.WeightedVariation) {
+	for i, weight := range w {
+		result = append(result, ldapi.WeightedVariation{Variation: int32(i), Weight: weight})
+	}
+	return result
+}
+
+func TestWeights(t *testing.T) {
+	specs := []struct {
+		name     string
+		original ldapi.ModelFallthrough
+		to       int
+		percent  float64
+		expected []ldapi.WeightedVariation
+	}{
+		{"from variation", ldapi.ModelFallthrough{Variation: 0}, 1, 5, weights(95000, 5000)},
+		{"full", ldapi.ModelFallthrough{Variation: 0}, 1, 100, weights(0, 100000)},
+		{"fraction", ldapi.ModelFallthrough{Variation: 1}, 0, 0.5, weights(500, 99500)},
+		{"from rollout", ldapi.ModelFallthrough{Rollout: &ldapi.Rollout{Variations: weights(0, 50000, 50000)}}, 0, 10,
+			weights(10000, 45000, 45000)},
+		{"rounding", ldapi.ModelFallthrough{Rollout: &ldapi.Rollout{Variations: weights(33333, 33334, 33333)}}, 2, 1,
+			weights(49499, 49501, 1000)},
+	}
+	for _, tt := range specs {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Weights(tt.original, len(tt.expected), tt.to, tt.percent)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+
+	_, err := Weights(ldapi.ModelFallthrough{Variation: 1}, 2, 1, 50)
+	assert.Error(t, err, "already serving the variation")
+	_, err = Weights(ldapi.ModelFallthrough{Variation: 0}, 2, 2, 50)
+	assert.Error(t, err, "unknown variation")
+}
+
+func TestLargestStep(t *testing.T) {
+	assert.Equal(t, 50.0, LargestStep(ldapi.ModelFallthrough{Variation: 0}, 1, []float64{1, 25, 50, 100}))
+	assert.Equal(t, 40.0, LargestStep(ldapi.ModelFallthrough{Rollout: &ldapi.Rollout{Variations: weights(80000, 20000)}},
+		1, []float64{60, 70}))
+	assert.Equal(t, 0.0, LargestStep(ldapi.ModelFallthrough{Variation: 1}, 1, nil))
+}
+
+func TestState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ramp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "ramps", "flag.json")
+
+	state, err := Load(filename)
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	original := State{Path: "/proj/env/flag", Steps: []float64{10, 100}, Step: 1, Status: StatusRunning}
+	require.NoError(t, original.Save(filename))
+	state, err = Load(filename)
+	require.NoError(t, err)
+	assert.Equal(t, original.Path, state.Path)
+	assert.Equal(t, 10.0, state.Percent())
+	assert.True(t, state.Active())
+}
+
+func TestLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ramp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "ramps", "flag.json")
+
+	unlock, err := Lock(filename)
+	require.NoError(t, err)
+	locked := make(chan time.Time)
+	go func() {
+		unlockAgain, err := Lock(filename)
+		assert.NoError(t, err)
+		locked <- time.Now()
+		unlockAgain()
+	}()
+	time.Sleep(3 * lockRetryInterval)
+	released := time.Now()
+	unlock()
+	assert.True(t, (<-locked).After(released), "the second lock waits for the first to be released")
+
+	// a lock left by a process that died is taken over
+	require.NoError(t, ioutil.WriteFile(filename+".lock", nil, 0600))
+	old := time.Now().Add(-2 * staleLockAge)
+	require.NoError(t, os.Chtimes(filename+".lock", old, old))
+	unlock, err = Lock(filename)
+	require.NoError(t, err)
+	unlock()
+	_, err = os.Stat(filename + ".lock")
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,134 @@
+package refs
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignorePattern is a single line from a .gitignore file
+type ignorePattern struct {
+	// base is the directory containing the .gitignore file, relative to the root of the scan
+	base    string
+	regex   *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreStack holds the patterns from every .gitignore file found so far.  Later patterns take precedence.
+type ignoreStack struct {
+	patterns []ignorePattern
+}
+
+func newIgnoreStack() *ignoreStack {
+	return &ignoreStack{}
+}
+
+// load reads the .gitignore file in dir, if there is one
+func (s *ignoreStack) load(dir string, rel string) error {
+	file, err := os.Open(filepath.Join(dir, ".gitignore")) // nolint:gosec // G304: Potential file inclusion via variable // ok because we are walking the tree
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close() // nolint:errcheck // ok because we only read the file
+
+	if rel == "." {
+		rel = ""
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if p, ok := parseIgnorePattern(rel, scanner.Text()); ok {
+			s.patterns = append(s.patterns, p)
+		}
+	}
+	return scanner.Err()
+}
+
+// ignored reports whether the path, relative to the root of the scan, is excluded
+func (s *ignoreStack) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, p := range s.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		target := rel
+		if p.base != "" {
+			if !strings.HasPrefix(rel, p.base+"/") {
+				continue
+			}
+			target = strings.TrimPrefix(rel, p.base+"/")
+		}
+		if p.regex.MatchString(target) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+func parseIgnorePattern(base string, line string) (ignorePattern, bool) {
+	line = strings.TrimRight(line, " ")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+	p := ignorePattern{base: base}
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+	line = strings.TrimPrefix(line, `\`)
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	// patterns without a slash match at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var expr strings.Builder
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		switch ch := line[i]; {
+		case strings.HasPrefix(line[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case ch == '*':
+			expr.WriteString("[^/]*")
+		case ch == '?':
+			expr.WriteString("[^/]")
+		case ch == '[':
+			end := strings.IndexByte(line[i:], ']')
+			if end < 0 {
+				expr.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	// a matching directory excludes everything beneath it
+	expr.WriteString("(?:/.*)?")
+
+	regex, err := regexp.Compile("^" + expr.String() + "$")
+	if err != nil {
+		return ignorePattern{}, false
+	}
+	p.regex = regex
+	return p, true
+}
@@ -0,0 +1,195 @@
+// Package refs finds references to flag keys in a source tree
+package refs
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultDelimiters are the characters that may surround a flag key in source code
+const DefaultDelimiters = "\"'`"
+
+const (
+	maxFileSize       = 1 << 20
+	maxContextLength  = 200
+	binarySniffLength = 8000
+)
+
+// DefaultPatterns are regular expressions, by file extension, that match calls to the LaunchDarkly sdks.
+// The first capturing group of each pattern is the flag key.
+var DefaultPatterns = map[string][]string{
+	".go":   {`\.(?:Bool|String|Int|Float64|JSON)Variation(?:Detail)?\(\s*"([^"]+)"`},
+	".js":   {`\.variation(?:Detail)?\(\s*['"` + "`" + `]([^'"` + "`" + `]+)`},
+	".jsx":  {`\.variation(?:Detail)?\(\s*['"` + "`" + `]([^'"` + "`" + `]+)`},
+	".ts":   {`\.variation(?:Detail)?\(\s*['"` + "`" + `]([^'"` + "`" + `]+)`},
+	".tsx":  {`\.variation(?:Detail)?\(\s*['"` + "`" + `]([^'"` + "`" + `]+)`},
+	".py":   {`\.variation(?:_detail)?\(\s*['"]([^'"]+)`},
+	".rb":   {`\.variation(?:_detail)?\(\s*['"]([^'"]+)`},
+	".java": {`\.(?:bool|string|int|double|json)Variation(?:Detail)?\(\s*"([^"]+)"`},
+	".kt":   {`\.(?:bool|string|int|double|json)Variation(?:Detail)?\(\s*"([^"]+)"`},
+	".cs":   {`\.(?:Bool|String|Int|Float|Json)Variation(?:Detail)?\(\s*"([^"]+)"`},
+	".php":  {`->variation\(\s*['"]([^'"]+)`},
+}
+
+// Reference is a single use of a flag key in a file
+type Reference struct {
+	Key     string `json:"key"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Context string `json:"context"`
+}
+
+// Result holds the outcome of a scan
+type Result struct {
+	// References are the uses of known flag keys
+	References []Reference `json:"references"`
+	// Unknown are sdk calls for flag keys that are not known
+	Unknown []Reference `json:"unknown"`
+	// Unused are known flag keys without any references
+	Unused []string `json:"unused"`
+}
+
+// Scanner searches files for flag keys
+type Scanner struct {
+	keys     map[string]bool
+	keyRegex *regexp.Regexp
+	patterns map[string][]*regexp.Regexp
+}
+
+// NewScanner creates a scanner for the given keys.  Keys are found when they are surrounded by any of the delimiters.
+func NewScanner(keys []string, delimiters string, patterns map[string][]string) (*Scanner, error) {
+	if delimiters == "" {
+		delimiters = DefaultDelimiters
+	}
+	s := &Scanner{keys: make(map[string]bool), patterns: make(map[string][]*regexp.Regexp)}
+
+	sorted := append([]string{}, keys...)
+	// prefer the longest key when one key is a prefix of another
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	var quoted []string
+	for _, k := range sorted {
+		s.keys[k] = true
+		quoted = append(quoted, regexp.QuoteMeta(k))
+	}
+	if len(quoted) > 0 {
+		delims := "[" + regexp.QuoteMeta(delimiters) + "]"
+		s.keyRegex = regexp.MustCompile(delims + "(" + strings.Join(quoted, "|") + ")" + delims)
+	}
+
+	for ext, exprs := range patterns {
+		for _, expr := range exprs {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, err
+			}
+			s.patterns[ext] = append(s.patterns[ext], re)
+		}
+	}
+	return s, nil
+}
+
+// Scan walks the tree at root, skipping anything excluded by .gitignore files
+func (s *Scanner) Scan(root string) (*Result, error) {
+	result := &Result{}
+	found := make(map[string]bool)
+	ignores := newIgnoreStack()
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if rel != "." && ignores.ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			return ignores.load(path, rel)
+		}
+		if !info.Mode().IsRegular() || info.Size() > maxFileSize || ignores.ignored(rel, false) {
+			return nil
+		}
+		refs, unknown, err := s.scanFile(path, rel)
+		if err != nil {
+			return err
+		}
+		for _, r := range refs {
+			found[r.Key] = true
+		}
+		result.References = append(result.References, refs...)
+		result.Unknown = append(result.Unknown, unknown...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for k := range s.keys {
+		if !found[k] {
+			result.Unused = append(result.Unused, k)
+		}
+	}
+	sort.Strings(result.Unused)
+	return result, nil
+}
+
+func (s *Scanner) scanFile(path string, rel string) (refs []Reference, unknown []Reference, err error) {
+	data, err := ioutil.ReadFile(path) // nolint:gosec // G304: Potential file inclusion via variable // ok because we are walking the tree
+	if err != nil {
+		return nil, nil, err
+	}
+	sniff := data
+	if len(sniff) > binarySniffLength {
+		sniff = sniff[:binarySniffLength]
+	}
+	if bytes.IndexByte(sniff, 0) >= 0 {
+		return nil, nil, nil
+	}
+
+	patterns := s.patterns[strings.ToLower(filepath.Ext(path))]
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), maxFileSize)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		context := strings.TrimSpace(line)
+		if len(context) > maxContextLength {
+			context = context[:maxContextLength] + "..."
+		}
+		for _, key := range s.findKeys(line) {
+			refs = append(refs, Reference{Key: key, File: rel, Line: lineNum, Context: context})
+		}
+		for _, re := range patterns {
+			for _, m := range re.FindAllStringSubmatch(line, -1) {
+				if len(m) > 1 && !s.keys[m[1]] {
+					unknown = append(unknown, Reference{Key: m[1], File: rel, Line: lineNum, Context: context})
+				}
+			}
+		}
+	}
+	return refs, unknown, scanner.Err()
+}
+
+// findKeys returns each key on the line, allowing adjacent keys to share a delimiter
+func (s *Scanner) findKeys(line string) (keys []string) {
+	if s.keyRegex == nil {
+		return nil
+	}
+	for pos := 0; pos < len(line); {
+		m := s.keyRegex.FindStringSubmatchIndex(line[pos:])
+		if m == nil {
+			break
+		}
+		keys = append(keys, line[pos+m[2]:pos+m[3]])
+		pos += m[1] - 1
+	}
+	return keys
+}
@@ -0,0 +1,94 @@
+package refs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	root, err := ioutil.TempDir("", "ldc-refs")
+	require.NoError(t, err)
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0700))
+		require.NoError(t, ioutil.WriteFile(p, []byte(content), 0600))
+	}
+	return root
+}
+
+func TestScan(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		".gitignore":        "vendor/\n*.log\n",
+		"main.go":           "package main\n\nfunc f() {\n\tclient.BoolVariation(\"flag-a\", user, false)\n\tclient.BoolVariation(\"gone-flag\", user, false)\n}\n",
+		"web/app.js":        "const x = ['flag-a','flag-ab'];\n",
+		"vendor/dep/dep.go": "\"flag-b\"\n",
+		"debug.log":         "\"flag-b\"\n",
+	})
+	defer os.RemoveAll(root) // nolint:errcheck
+
+	scanner, err := NewScanner([]string{"flag-a", "flag-ab", "flag-b"}, "", DefaultPatterns)
+	require.NoError(t, err)
+	result, err := scanner.Scan(root)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Reference{
+		{Key: "flag-a", File: "main.go", Line: 4, Context: `client.BoolVariation("flag-a", user, false)`},
+		{Key: "flag-a", File: "web/app.js", Line: 1, Context: `const x = ['flag-a','flag-ab'];`},
+		{Key: "flag-ab", File: "web/app.js", Line: 1, Context: `const x = ['flag-a','flag-ab'];`},
+	}, result.References)
+	assert.Equal(t, []Reference{
+		{Key: "gone-flag", File: "main.go", Line: 5, Context: `client.BoolVariation("gone-flag", user, false)`},
+	}, result.Unknown)
+	assert.Equal(t, []string{"flag-b"}, result.Unused)
+}
+
+func TestCustomDelimiters(t *testing.T) {
+	scanner, err := NewScanner([]string{"flag-a"}, "<>", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"flag-a"}, scanner.findKeys("<flag-a>"))
+	assert.Empty(t, scanner.findKeys(`"flag-a"`))
+}
+
+func TestIgnorePatterns(t *testing.T) {
+	specs := []struct {
+		base    string
+		pattern string
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"", "*.log", "a/b/debug.log", false, true},
+		{"", "/build", "build", true, true},
+		{"", "/build", "src/build", true, false},
+		{"", "docs/*.md", "docs/readme.md", false, true},
+		{"", "docs/*.md", "docs/sub/readme.md", false, false},
+		{"", "**/generated", "a/b/generated/x.go", false, true},
+		{"", "tmp/", "tmp", false, false},
+		{"sub", "*.go", "sub/x.go", false, true},
+		{"sub", "*.go", "x.go", false, false},
+	}
+	for _, tt := range specs {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			p, ok := parseIgnorePattern(tt.base, tt.pattern)
+			require.True(t, ok)
+			s := &ignoreStack{patterns: []ignorePattern{p}}
+			assert.Equal(t, tt.ignored, s.ignored(tt.path, tt.isDir))
+		})
+	}
+}
+
+func TestNegatedIgnorePattern(t *testing.T) {
+	s := &ignoreStack{}
+	for _, line := range []string{"*.log", "!keep.log"} {
+		p, ok := parseIgnorePattern("", line)
+		require.True(t, ok)
+		s.patterns = append(s.patterns, p)
+	}
+	assert.True(t, s.ignored("other.log", false))
+	assert.False(t, s.ignored("keep.log", false))
+}
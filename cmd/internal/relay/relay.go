@@ -0,0 +1,271 @@
+// Package relay serves flag data to server-side sdks over the same streaming and polling endpoints as the
+// LaunchDarkly service
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/ldc/cmd/internal/sdkdata"
+)
+
+// DefaultHeartbeat is how often idle streams are sent a comment to keep connections open
+const DefaultHeartbeat = 3 * time.Minute
+
+// subscriberBuffer is the number of events a stream may fall behind before it is disconnected
+const subscriberBuffer = 100
+
+const (
+	flagsPath    = "/flags/"
+	segmentsPath = "/segments/"
+)
+
+type event struct {
+	name string
+	data []byte
+}
+
+// Server holds the current data and pushes changes to connected streams
+type Server struct {
+	Heartbeat time.Duration
+
+	mu          sync.RWMutex
+	data        sdkdata.Data
+	subscribers map[chan event]struct{}
+}
+
+// NewServer creates a server for data
+func NewServer(data sdkdata.Data) *Server {
+	return &Server{
+		Heartbeat:   DefaultHeartbeat,
+		data:        data,
+		subscribers: make(map[chan event]struct{}),
+	}
+}
+
+// Update replaces the served data, sending a patch or delete event to connected streams for each flag or segment
+// that changed.  Versions of changed items are increased if needed so sdks do not discard the change.  It returns
+// the number of changes.
+func (s *Server) Update(data sdkdata.Data) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []event
+	next := sdkdata.Data{Flags: make(map[string]sdkdata.Flag), Segments: make(map[string]sdkdata.Segment)}
+	for key, flag := range data.Flags {
+		old, exists := s.data.Flags[key]
+		if exists {
+			changed, err := flagChanged(old, flag)
+			if err != nil {
+				return 0, err
+			}
+			if !changed {
+				next.Flags[key] = old
+				continue
+			}
+			if flag.Version <= old.Version {
+				flag.Version = old.Version + 1
+			}
+		}
+		next.Flags[key] = flag
+		e, err := patchEvent(flagsPath+key, flag)
+		if err != nil {
+			return 0, err
+		}
+		events = append(events, e)
+	}
+	for key, old := range s.data.Flags {
+		if _, ok := data.Flags[key]; !ok {
+			events = append(events, deleteEvent(flagsPath+key, old.Version+1))
+		}
+	}
+
+	for key, segment := range data.Segments {
+		old, exists := s.data.Segments[key]
+		if exists {
+			changed, err := segmentChanged(old, segment)
+			if err != nil {
+				return 0, err
+			}
+			if !changed {
+				next.Segments[key] = old
+				continue
+			}
+			if segment.Version <= old.Version {
+				segment.Version = old.Version + 1
+			}
+		}
+		next.Segments[key] = segment
+		e, err := patchEvent(segmentsPath+key, segment)
+		if err != nil {
+			return 0, err
+		}
+		events = append(events, e)
+	}
+	for key, old := range s.data.Segments {
+		if _, ok := data.Segments[key]; !ok {
+			events = append(events, deleteEvent(segmentsPath+key, old.Version+1))
+		}
+	}
+
+	s.data = next
+	for _, e := range events {
+		s.broadcast(e)
+	}
+	return len(events), nil
+}
+
+// broadcast sends an event to all streams, disconnecting those that are too far behind so they reconnect and
+// receive a fresh put event
+func (s *Server) broadcast(e event) {
+	for ch := range s.subscribers {
+		select {
+		case ch <- e:
+		default:
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// flagChanged compares two versions of a flag, ignoring their version numbers
+func flagChanged(a, b sdkdata.Flag) (bool, error) {
+	a.Version, b.Version = 0, 0
+	return jsonDiffers(a, b)
+}
+
+// segmentChanged compares two versions of a segment, ignoring their version numbers
+func segmentChanged(a, b sdkdata.Segment) (bool, error) {
+	a.Version, b.Version = 0, 0
+	return jsonDiffers(a, b)
+}
+
+func jsonDiffers(a, b interface{}) (bool, error) {
+	aj, err := json.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+	bj, err := json.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+	return string(aj) != string(bj), nil
+}
+
+func patchEvent(path string, item interface{}) (event, error) {
+	data, err := json.Marshal(map[string]interface{}{"path": path, "data": item})
+	return event{name: "patch", data: data}, err
+}
+
+func deleteEvent(path string, version int32) event {
+	data, _ := json.Marshal(map[string]interface{}{"path": path, "version": version})
+	return event{name: "delete", data: data}
+}
+
+// ServeHTTP handles the streaming endpoint /all and the polling endpoints under /sdk
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	switch {
+	case r.URL.Path == "/all":
+		s.stream(w, r)
+	case r.URL.Path == "/sdk/latest-all":
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		writeJSON(w, s.data)
+	case r.URL.Path == "/sdk/latest-flags":
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		writeJSON(w, s.data.Flags)
+	case strings.HasPrefix(r.URL.Path, "/sdk/latest-flags/"):
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		flag, ok := s.data.Flags[strings.TrimPrefix(r.URL.Path, "/sdk/latest-flags/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, flag)
+	case r.URL.Path == "/sdk/latest-segments":
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		writeJSON(w, s.data.Segments)
+	case strings.HasPrefix(r.URL.Path, "/sdk/latest-segments/"):
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		segment, ok := s.data.Segments[strings.TrimPrefix(r.URL.Path, "/sdk/latest-segments/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, segment)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan event, subscriberBuffer)
+	s.mu.Lock()
+	put, err := json.Marshal(map[string]interface{}{"path": "/", "data": s.data})
+	if err != nil {
+		s.mu.Unlock()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	writeEvent(w, event{name: "put", data: put})
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(s.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			writeEvent(w, e)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ":\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e event) {
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.name, e.data)
+}
+
+func writeJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
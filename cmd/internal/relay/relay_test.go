@@ -0,0 +1,109 @@
+package relay
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/launchdarkly/ldc/cmd/internal/sdkdata"
+)
+
+func testData(value interface{}) sdkdata.Data {
+	zero := int32(0)
+	return sdkdata.Data{
+		Flags: map[string]sdkdata.Flag{
+			"flag": {Key: "flag", Version: 1, On: true, Fallthrough: sdkdata.VariationOrRollout{Variation: &zero}, Variations: []interface{}{value}},
+		},
+		Segments: map[string]sdkdata.Segment{
+			"segment": {Key: "segment", Version: 1, Included: []string{"user"}},
+		},
+	}
+}
+
+func TestPolling(t *testing.T) {
+	server := NewServer(testData("a"))
+	specs := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/sdk/latest-flags/flag", http.StatusOK, `"key":"flag"`},
+		{"/sdk/latest-flags/missing", http.StatusNotFound, ""},
+		{"/sdk/latest-segments/segment", http.StatusOK, `"included":["user"]`},
+		{"/sdk/latest-all", http.StatusOK, `"segments":{"segment"`},
+		{"/unknown", http.StatusNotFound, ""},
+	}
+	for _, tt := range specs {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), tt.body)
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	server := NewServer(testData("a"))
+
+	changes, err := server.Update(testData("a"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, changes)
+
+	data := testData("b")
+	delete(data.Segments, "segment")
+	changes, err = server.Update(data)
+	require.NoError(t, err)
+	assert.Equal(t, 2, changes)
+	assert.Equal(t, int32(2), server.data.Flags["flag"].Version, "version is increased so sdks apply the change")
+}
+
+func TestStream(t *testing.T) {
+	server := NewServer(testData("a"))
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/all")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	reader := bufio.NewReader(resp.Body)
+
+	name, data := readEvent(t, reader)
+	assert.Equal(t, "put", name)
+	assert.Contains(t, data, `"path":"/"`)
+
+	_, err = server.Update(testData("b"))
+	require.NoError(t, err)
+	name, data = readEvent(t, reader)
+	assert.Equal(t, "patch", name)
+	var patch struct {
+		Path string
+		Data sdkdata.Flag
+	}
+	require.NoError(t, json.Unmarshal([]byte(data), &patch))
+	assert.Equal(t, "/flags/flag", patch.Path)
+	assert.Equal(t, []interface{}{"b"}, patch.Data.Variations)
+}
+
+func readEvent(t *testing.T, reader *bufio.Reader) (name string, data string) {
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && name != "":
+			return name, data
+		}
+	}
+}
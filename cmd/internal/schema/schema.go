@@ -0,0 +1,240 @@
+// Package schema validates json documents against the definitions in the LaunchDarkly openapi spec
+package schema
+
+import (
+	// embed the spec
+	_ "embed"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The spec is a copy of the one bundled with the api client, which cannot be embedded from the vendor directory
+//go:generate cp ../../../vendor/github.com/launchdarkly/api-client-go/openapi.yml openapi.yml
+
+//go:embed openapi.yml
+var spec []byte
+
+// Schema is a swagger schema object
+type Schema struct {
+	Ref                  string             `yaml:"$ref"`
+	Type                 string             `yaml:"type"`
+	Properties           map[string]*Schema `yaml:"properties"`
+	AdditionalProperties *Schema            `yaml:"additionalProperties"`
+	Items                *Schema            `yaml:"items"`
+	Enum                 []interface{}      `yaml:"enum"`
+	Required             []string           `yaml:"required"`
+}
+
+// Problem is a part of a document that does not match the schema
+type Problem struct {
+	// Path is the json pointer to the value
+	Path    string
+	Message string
+	// Unknown is true if the problem is only that the property is not in the spec, which may be out of date
+	Unknown bool
+}
+
+func (p Problem) String() string {
+	path := p.Path
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("%s: %s", path, p.Message)
+}
+
+var (
+	loadOnce    sync.Once
+	definitions map[string]*Schema
+	loadErr     error
+)
+
+func load() (map[string]*Schema, error) {
+	loadOnce.Do(func() {
+		var doc struct {
+			Definitions map[string]*Schema `yaml:"definitions"`
+		}
+		loadErr = yaml.Unmarshal(spec, &doc)
+		definitions = doc.Definitions
+	})
+	return definitions, loadErr
+}
+
+// Validate checks a decoded json document against a definition in the spec
+func Validate(definition string, doc interface{}) ([]Problem, error) {
+	defs, err := load()
+	if err != nil {
+		return nil, err
+	}
+	s, ok := defs[definition]
+	if !ok {
+		return nil, fmt.Errorf(`no definition for "%s"`, definition)
+	}
+	v := validator{definitions: defs}
+	if err := v.validate("", s, doc); err != nil {
+		return nil, err
+	}
+	return v.problems, nil
+}
+
+type validator struct {
+	definitions map[string]*Schema
+	problems    []Problem
+}
+
+func (v *validator) resolve(s *Schema) (*Schema, error) {
+	for s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/definitions/")
+		next, ok := v.definitions[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown reference %s", s.Ref)
+		}
+		s = next
+	}
+	return s, nil
+}
+
+func (v *validator) add(path string, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validate(path string, s *Schema, value interface{}) error {
+	s, err := v.resolve(s)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		return nil
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		v.add(path, "must be one of %s", formatEnum(s.Enum))
+	}
+
+	typ := s.Type
+	if typ == "" && (s.Properties != nil || s.AdditionalProperties != nil) {
+		typ = "object"
+	}
+	switch typ {
+	case "object":
+		if s.Properties == nil && s.AdditionalProperties == nil {
+			// the spec uses an object without properties for values of any type
+			return nil
+		}
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			v.add(path, "must be an object")
+			return nil
+		}
+		return v.validateObject(path, s, m)
+	case "array":
+		a, ok := value.([]interface{})
+		if !ok {
+			v.add(path, "must be an array")
+			return nil
+		}
+		if s.Items == nil {
+			return nil
+		}
+		for i, item := range a {
+			if err := v.validate(fmt.Sprintf("%s/%d", path, i), s.Items, item); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			v.add(path, "must be a string")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.add(path, "must be a boolean")
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			v.add(path, "must be a number")
+		}
+	case "integer":
+		if f, ok := value.(float64); !ok || f != math.Trunc(f) {
+			v.add(path, "must be an integer")
+		}
+	}
+	return nil
+}
+
+func (v *validator) validateObject(path string, s *Schema, m map[string]interface{}) error {
+	for _, name := range s.Required {
+		if _, ok := m[name]; !ok {
+			v.add(path, `missing required property "%s"`, name)
+		}
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		propPath := path + "/" + escape(k)
+		prop, ok := s.Properties[k]
+		if !ok {
+			prop = s.AdditionalProperties
+		}
+		if prop == nil {
+			v.problems = append(v.problems, Problem{Path: propPath, Message: "unknown property", Unknown: true})
+			continue
+		}
+		if err := v.validate(propPath, prop, m[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		if reflect.DeepEqual(e, value) || fmt.Sprint(e) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(enum []interface{}) string {
+	values := make([]string, 0, len(enum))
+	for _, e := range enum {
+		values = append(values, fmt.Sprint(e))
+	}
+	return strings.Join(values, ", ")
+}
+
+func escape(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
+
+// readOnlyProperties are set by the server.  The spec does not mark them, so they are listed here along with any
+// property starting with an underscore, like _links and _maintainer.
+var readOnlyProperties = map[string]bool{
+	"apiKey":       true,
+	"creationDate": true,
+	"lastModified": true,
+	"mobileKey":    true,
+	"version":      true,
+}
+
+// ReadOnly returns true if a json pointer refers to a property set by the server, or to something inside one
+func ReadOnly(pointer string) bool {
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, token := range tokens {
+		if strings.HasPrefix(token, "_") {
+			return true
+		}
+		if i == len(tokens)-1 && readOnlyProperties[token] {
+			return true
+		}
+	}
+	return false
+}
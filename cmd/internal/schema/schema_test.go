@@ -0,0 +1,81 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	var v interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &v))
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	specs := []struct {
+		name     string
+		doc      string
+		problems []string
+		unknown  []string
+	}{
+		{"valid", `{"key": "a", "name": "A", "temporary": true, "tags": ["x"], "_version": 2,
+			"environments": {"production": {"on": true, "rules": [{"variation": 1, "clauses": []}]}}}`, nil, nil},
+		{"wrong types", `{"key": 1, "tags": "x", "_version": 1.5}`,
+			[]string{"/_version: must be an integer", "/key: must be a string", "/tags: must be an array"}, nil},
+		{"nested", `{"environments": {"production": {"on": "yes", "targets": [{"values": [1]}]}}}`,
+			[]string{"/environments/production/on: must be a boolean", "/environments/production/targets/0/values/0: must be a string"}, nil},
+		{"unknown", `{"nmae": "A", "environments": {"production": {"fallthrough": {"rollout": {"bucketBy": "email"}}}}}`,
+			nil, []string{"/environments/production/fallthrough/rollout/bucketBy", "/nmae"}},
+		{"not an object", `[]`, []string{"/: must be an object"}, nil},
+		{"null", `{"description": null}`, nil, nil},
+		{"any value", `{"variations": [{"value": 1}, {"value": {"a": [1]}}]}`, nil, nil},
+	}
+	for _, tt := range specs {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Validate("FeatureFlag", decode(t, tt.doc))
+			require.NoError(t, err)
+			var problems, unknown []string
+			for _, p := range result {
+				if p.Unknown {
+					unknown = append(unknown, p.Path)
+				} else {
+					problems = append(problems, p.String())
+				}
+			}
+			assert.Equal(t, tt.problems, problems)
+			assert.Equal(t, tt.unknown, unknown)
+		})
+	}
+}
+
+func TestValidateRequiredAndEnum(t *testing.T) {
+	result, err := Validate("Statement", decode(t, `{"effect": "maybe"}`))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "/effect: must be one of allow, deny", result[0].String())
+}
+
+func TestValidateUnknownDefinition(t *testing.T) {
+	_, err := Validate("Nothing", decode(t, `{}`))
+	assert.Error(t, err)
+}
+
+func TestReadOnly(t *testing.T) {
+	for pointer, expected := range map[string]bool{
+		"/_links/self/href":                    true,
+		"/_maintainer":                         true,
+		"/_version":                            true,
+		"/creationDate":                        true,
+		"/environments/production/version":     true,
+		"/environments/production/rules/0/_id": true,
+		"/apiKey":                              true,
+		"/name":                                false,
+		"/environments/production/on":          false,
+		"/variations/0/value":                  false,
+	} {
+		assert.Equal(t, expected, ReadOnly(pointer), pointer)
+	}
+}
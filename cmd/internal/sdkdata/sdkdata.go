@@ -0,0 +1,313 @@
+// Package sdkdata converts flags and segments into the data format used by the LaunchDarkly server-side sdks,
+// which is also the format accepted by their file data sources
+package sdkdata
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Data holds all flags and segments for an environment
+type Data struct {
+	Flags    map[string]Flag    `json:"flags"`
+	Segments map[string]Segment `json:"segments"`
+}
+
+// FlagValues is the simplified file data source format that only sets the value of each flag
+type FlagValues struct {
+	FlagValues map[string]interface{} `json:"flagValues"`
+}
+
+// Flag is the sdk representation of a flag in a single environment
+type Flag struct {
+	Key           string             `json:"key"`
+	Version       int32              `json:"version"`
+	On            bool               `json:"on"`
+	Prerequisites []Prerequisite     `json:"prerequisites"`
+	Salt          string             `json:"salt"`
+	Targets       []Target           `json:"targets"`
+	Rules         []Rule             `json:"rules"`
+	Fallthrough   VariationOrRollout `json:"fallthrough"`
+	OffVariation  *int32             `json:"offVariation"`
+	Variations    []interface{}      `json:"variations"`
+	TrackEvents   bool               `json:"trackEvents"`
+	ClientSide    bool               `json:"clientSide"`
+	Deleted       bool               `json:"deleted"`
+}
+
+// Prerequisite is a flag that must return a variation before another flag is evaluated
+type Prerequisite struct {
+	Key       string `json:"key"`
+	Variation int32  `json:"variation"`
+}
+
+// Target assigns a variation to specific users
+type Target struct {
+	Values    []string `json:"values"`
+	Variation int32    `json:"variation"`
+}
+
+// VariationOrRollout is either a fixed variation or a percentage rollout
+type VariationOrRollout struct {
+	Variation *int32   `json:"variation,omitempty"`
+	Rollout   *Rollout `json:"rollout,omitempty"`
+}
+
+// Rollout splits users between variations
+type Rollout struct {
+	Variations []WeightedVariation `json:"variations"`
+	BucketBy   string              `json:"bucketBy,omitempty"`
+}
+
+// WeightedVariation is a variation and its share of a rollout in thousandths of a percent
+type WeightedVariation struct {
+	Variation int32 `json:"variation"`
+	Weight    int32 `json:"weight"`
+}
+
+// Rule assigns a variation or rollout to users matching all of its clauses
+type Rule struct {
+	ID string `json:"id,omitempty"`
+	VariationOrRollout
+	Clauses     []Clause `json:"clauses"`
+	TrackEvents bool     `json:"trackEvents"`
+}
+
+// Clause tests a user attribute
+type Clause struct {
+	Attribute string        `json:"attribute"`
+	Op        string        `json:"op"`
+	Values    []interface{} `json:"values"`
+	Negate    bool          `json:"negate"`
+}
+
+// Segment is the sdk representation of a user segment
+type Segment struct {
+	Key      string        `json:"key"`
+	Version  int32         `json:"version"`
+	Included []string      `json:"included"`
+	Excluded []string      `json:"excluded"`
+	Salt     string        `json:"salt"`
+	Rules    []SegmentRule `json:"rules"`
+	Deleted  bool          `json:"deleted"`
+}
+
+// SegmentRule includes users matching all of its clauses in a segment
+type SegmentRule struct {
+	Clauses  []Clause `json:"clauses"`
+	Weight   *int32   `json:"weight,omitempty"`
+	BucketBy string   `json:"bucketBy,omitempty"`
+}
+
+// APIFlag is a flag as the LaunchDarkly api returns it.  Flags are read from the json of the api rather than through
+// the api client, which drops null off variations and the attributes that rollouts bucket users by.
+type APIFlag struct {
+	Key              string                   `json:"key"`
+	Version          int32                    `json:"_version"`
+	IncludeInSnippet bool                     `json:"includeInSnippet"`
+	Variations       []APIVariation           `json:"variations"`
+	Environments     map[string]APIFlagConfig `json:"environments"`
+}
+
+// APIVariation is a variation of an api flag
+type APIVariation struct {
+	Value interface{} `json:"value"`
+}
+
+// APIFlagConfig is the configuration of an api flag in an environment
+type APIFlagConfig struct {
+	On            bool               `json:"on"`
+	Salt          string             `json:"salt"`
+	Version       int32              `json:"version"`
+	Prerequisites []Prerequisite     `json:"prerequisites"`
+	Targets       []Target           `json:"targets"`
+	Rules         []APIRule          `json:"rules"`
+	Fallthrough   VariationOrRollout `json:"fallthrough"`
+	OffVariation  *int32             `json:"offVariation"`
+	TrackEvents   bool               `json:"trackEvents"`
+}
+
+// APIRule is a targeting rule of an api flag, which the api identifies by _id
+type APIRule struct {
+	ID string `json:"_id"`
+	VariationOrRollout
+	Clauses     []Clause `json:"clauses"`
+	TrackEvents bool     `json:"trackEvents"`
+}
+
+// APISegment is a user segment as the LaunchDarkly api returns it
+type APISegment struct {
+	Key      string        `json:"key"`
+	Version  int32         `json:"version"`
+	Included []string      `json:"included"`
+	Excluded []string      `json:"excluded"`
+	Salt     string        `json:"salt"`
+	Rules    []SegmentRule `json:"rules"`
+	Deleted  bool          `json:"deleted"`
+}
+
+// FromAPIFlag converts the configuration of a flag in an environment.  It returns false if the flag is not
+// configured for the environment.
+func FromAPIFlag(flag APIFlag, env string) (Flag, bool) {
+	config, ok := flag.Environments[env]
+	if !ok {
+		return Flag{}, false
+	}
+	f := Flag{
+		Key:           flag.Key,
+		Version:       config.Version,
+		On:            config.On,
+		Prerequisites: append([]Prerequisite{}, config.Prerequisites...),
+		Salt:          config.Salt,
+		Targets:       []Target{},
+		Rules:         []Rule{},
+		Fallthrough:   variationOrRollout(config.Fallthrough),
+		OffVariation:  config.OffVariation,
+		Variations:    []interface{}{},
+		TrackEvents:   config.TrackEvents,
+		ClientSide:    flag.IncludeInSnippet,
+	}
+	if f.Version == 0 {
+		f.Version = flag.Version
+	}
+	for _, t := range config.Targets {
+		f.Targets = append(f.Targets, Target{Values: append([]string{}, t.Values...), Variation: t.Variation})
+	}
+	for _, r := range config.Rules {
+		f.Rules = append(f.Rules, Rule{
+			ID:                 r.ID,
+			VariationOrRollout: variationOrRollout(r.VariationOrRollout),
+			Clauses:            clauses(r.Clauses),
+			TrackEvents:        r.TrackEvents,
+		})
+	}
+	for _, v := range flag.Variations {
+		f.Variations = append(f.Variations, v.Value)
+	}
+	return f, true
+}
+
+// FromAPISegment converts a user segment
+func FromAPISegment(segment APISegment) Segment {
+	s := Segment{
+		Key:      segment.Key,
+		Version:  segment.Version,
+		Included: append([]string{}, segment.Included...),
+		Excluded: append([]string{}, segment.Excluded...),
+		Salt:     segment.Salt,
+		Rules:    []SegmentRule{},
+		Deleted:  segment.Deleted,
+	}
+	for _, r := range segment.Rules {
+		s.Rules = append(s.Rules, SegmentRule{Clauses: clauses(r.Clauses), Weight: r.Weight, BucketBy: r.BucketBy})
+	}
+	return s
+}
+
+// CurrentValue returns the value most users currently receive for a flag in an environment: the off variation if
+// the flag is off, otherwise the fallthrough variation or the largest share of a fallthrough rollout
+func CurrentValue(flag Flag) interface{} {
+	index := int32(-1)
+	switch {
+	case !flag.On:
+		if flag.OffVariation != nil {
+			index = *flag.OffVariation
+		}
+	case flag.Fallthrough.Rollout != nil:
+		var largest int32 = -1
+		for _, wv := range flag.Fallthrough.Rollout.Variations {
+			if wv.Weight > largest {
+				largest = wv.Weight
+				index = wv.Variation
+			}
+		}
+	case flag.Fallthrough.Variation != nil:
+		index = *flag.Fallthrough.Variation
+	}
+	if index < 0 || int(index) >= len(flag.Variations) {
+		return nil
+	}
+	return flag.Variations[index]
+}
+
+// ToFlagValues converts data to the simplified format
+func ToFlagValues(data Data) FlagValues {
+	values := FlagValues{FlagValues: make(map[string]interface{})}
+	for key, flag := range data.Flags {
+		values.FlagValues[key] = CurrentValue(flag)
+	}
+	return values
+}
+
+// variationOrRollout copies a variation or rollout, serving the first variation if it has neither
+func variationOrRollout(from VariationOrRollout) VariationOrRollout {
+	if from.Rollout == nil {
+		variation := int32(0)
+		if from.Variation != nil {
+			variation = *from.Variation
+		}
+		return VariationOrRollout{Variation: &variation}
+	}
+	r := &Rollout{Variations: append([]WeightedVariation{}, from.Rollout.Variations...), BucketBy: from.Rollout.BucketBy}
+	return VariationOrRollout{Rollout: r}
+}
+
+func clauses(from []Clause) []Clause {
+	result := []Clause{}
+	for _, c := range from {
+		if c.Values == nil {
+			c.Values = []interface{}{}
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+// Parse reads data in either the full or the simplified file data source format.  Simplified flags are converted
+// to flags that always return their value.
+func Parse(bytes []byte) (Data, error) {
+	var file struct {
+		Flags      map[string]Flag        `json:"flags"`
+		Segments   map[string]Segment     `json:"segments"`
+		FlagValues map[string]interface{} `json:"flagValues"`
+	}
+	if err := json.Unmarshal(bytes, &file); err != nil {
+		return Data{}, err
+	}
+	data := Data{Flags: file.Flags, Segments: file.Segments}
+	if data.Flags == nil {
+		data.Flags = make(map[string]Flag)
+	}
+	if data.Segments == nil {
+		data.Segments = make(map[string]Segment)
+	}
+	for key, value := range file.FlagValues {
+		if _, ok := data.Flags[key]; ok {
+			return Data{}, fmt.Errorf(`flag "%s" is in both flags and flagValues`, key)
+		}
+		data.Flags[key] = valueFlag(key, value)
+	}
+	for key, flag := range data.Flags {
+		flag.Key = key
+		data.Flags[key] = flag
+	}
+	for key, segment := range data.Segments {
+		segment.Key = key
+		data.Segments[key] = segment
+	}
+	return data, nil
+}
+
+func valueFlag(key string, value interface{}) Flag {
+	zero := int32(0)
+	return Flag{
+		Key:           key,
+		Version:       1,
+		On:            true,
+		Prerequisites: []Prerequisite{},
+		Targets:       []Target{},
+		Rules:         []Rule{},
+		Fallthrough:   VariationOrRollout{Variation: &zero},
+		Variations:    []interface{}{value},
+	}
+}
@@ -0,0 +1,85 @@
+package sdkdata
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromAPIFlag(t *testing.T) {
+	var flag APIFlag
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"key": "flag", "_version": 7, "includeInSnippet": true, "variations": [{"value": "a"}, {"value": "b"}],
+		"environments": {"production": {
+			"on": true, "salt": "salt", "version": 3,
+			"rules": [{"_id": "rule", "clauses": [{"attribute": "email", "op": "endsWith", "values": ["@example.com"]}],
+				"rollout": {"variations": [{"variation": 0, "weight": 30000}, {"variation": 1, "weight": 70000}], "bucketBy": "email"}}],
+			"fallthrough": {"variation": 0}, "offVariation": null
+		}}
+	}`), &flag))
+
+	_, ok := FromAPIFlag(flag, "test")
+	assert.False(t, ok)
+
+	f, ok := FromAPIFlag(flag, "production")
+	require.True(t, ok)
+	data, err := json.Marshal(f)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"key": "flag", "version": 3, "on": true, "prerequisites": [], "salt": "salt", "targets": [],
+		"rules": [{"id": "rule", "rollout": {"variations": [{"variation": 0, "weight": 30000}, {"variation": 1, "weight": 70000}], "bucketBy": "email"},
+			"clauses": [{"attribute": "email", "op": "endsWith", "values": ["@example.com"], "negate": false}], "trackEvents": false}],
+		"fallthrough": {"variation": 0}, "offVariation": null, "variations": ["a", "b"],
+		"trackEvents": false, "clientSide": true, "deleted": false
+	}`, string(data))
+}
+
+func TestFromAPISegment(t *testing.T) {
+	var segment APISegment
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"key": "segment", "version": 2, "included": ["a"], "salt": "salt",
+		"rules": [{"clauses": [{"attribute": "country", "op": "in", "values": ["nz"]}], "weight": 50000, "bucketBy": "email"}]
+	}`), &segment))
+
+	data, err := json.Marshal(FromAPISegment(segment))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"key": "segment", "version": 2, "included": ["a"], "excluded": [], "salt": "salt",
+		"rules": [{"clauses": [{"attribute": "country", "op": "in", "values": ["nz"], "negate": false}], "weight": 50000, "bucketBy": "email"}],
+		"deleted": false
+	}`, string(data))
+}
+
+func TestCurrentValue(t *testing.T) {
+	zero, one := int32(0), int32(1)
+	variations := []interface{}{"a", "b"}
+	specs := []struct {
+		name     string
+		flag     Flag
+		expected interface{}
+	}{
+		{"off", Flag{On: false, OffVariation: &one, Variations: variations}, "b"},
+		{"fallthrough", Flag{On: true, Fallthrough: VariationOrRollout{Variation: &zero}, Variations: variations}, "a"},
+		{"rollout", Flag{On: true, Fallthrough: VariationOrRollout{Rollout: &Rollout{Variations: []WeightedVariation{
+			{Variation: 0, Weight: 40000}, {Variation: 1, Weight: 60000}}}}, Variations: variations}, "b"},
+		{"missing variation", Flag{On: false, Variations: variations}, nil},
+	}
+	for _, tt := range specs {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, CurrentValue(tt.flag))
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	data, err := Parse([]byte(`{"flags": {"a": {"on": true, "variations": [1, 2]}}, "flagValues": {"b": "value"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "a", data.Flags["a"].Key)
+	assert.Equal(t, "value", CurrentValue(data.Flags["b"]))
+	assert.NotNil(t, data.Segments)
+
+	_, err = Parse([]byte(`{"flags": {"a": {}}, "flagValues": {"a": true}}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,139 @@
+// Package sdkkeys formats the keys that SDKs use to connect to an environment as configuration for services
+package sdkkeys
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Formats
+const (
+	FormatDotenv    = "dotenv"
+	FormatJSON      = "json"
+	FormatK8sSecret = "k8s-secret"
+	FormatShell     = "shell"
+)
+
+// Formats are the supported formats
+var Formats = []string{FormatDotenv, FormatJSON, FormatK8sSecret, FormatShell}
+
+// variablePrefix starts the name of every variable
+const variablePrefix = "LD_"
+
+// visibleChars is the number of characters left at each end of a masked key
+const visibleChars = 4
+
+var (
+	nonVariableChars = regexp.MustCompile(`[^A-Z0-9]+`)
+	nonNameChars     = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Keys are the keys of an environment
+type Keys struct {
+	Environment  string `json:"-"`
+	SDKKey       string `json:"sdkKey"`
+	MobileKey    string `json:"mobileKey"`
+	ClientSideID string `json:"clientSideId"`
+}
+
+// Masked returns the keys with the secret ones masked.  The client-side id is left as it is because it is public.
+func (k Keys) Masked() Keys {
+	k.SDKKey = Mask(k.SDKKey)
+	k.MobileKey = Mask(k.MobileKey)
+	return k
+}
+
+// Mask hides all but the ends of a key, so that it can be recognized without being usable
+func Mask(key string) string {
+	if len(key) <= 3*visibleChars {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:visibleChars] + strings.Repeat("*", len(key)-2*visibleChars) + key[len(key)-visibleChars:]
+}
+
+type variable struct {
+	name  string
+	value string
+}
+
+// variables returns the variables for an environment.  Their names include the environment when there are keys for
+// more than one.
+func (k Keys) variables(qualified bool) []variable {
+	prefix := variablePrefix
+	if qualified {
+		prefix += strings.Trim(nonVariableChars.ReplaceAllString(strings.ToUpper(k.Environment), "_"), "_") + "_"
+	}
+	return []variable{
+		{prefix + "SDK_KEY", k.SDKKey},
+		{prefix + "MOBILE_KEY", k.MobileKey},
+		{prefix + "CLIENT_SIDE_ID", k.ClientSideID},
+	}
+}
+
+// Format writes the keys of one or more environments in the given format
+func Format(keys []Keys, format string) (string, error) {
+	buf := bytes.Buffer{}
+	qualified := len(keys) > 1
+	switch format {
+	case FormatDotenv, FormatShell:
+		for i, k := range keys {
+			if qualified {
+				if i > 0 {
+					buf.WriteString("\n")
+				}
+				fmt.Fprintf(&buf, "# %s\n", k.Environment)
+			}
+			for _, v := range k.variables(qualified) {
+				if format == FormatShell {
+					fmt.Fprintf(&buf, "export %s=%s\n", v.name, shellQuote(v.value))
+				} else {
+					fmt.Fprintf(&buf, "%s=%s\n", v.name, v.value)
+				}
+			}
+		}
+	case FormatJSON:
+		var data []byte
+		var err error
+		if qualified {
+			byEnvironment := make(map[string]Keys, len(keys))
+			for _, k := range keys {
+				byEnvironment[k.Environment] = k
+			}
+			data, err = json.MarshalIndent(byEnvironment, "", "  ")
+		} else if len(keys) == 1 {
+			data, err = json.MarshalIndent(keys[0], "", "  ")
+		}
+		if err != nil {
+			return "", err
+		}
+		buf.Write(data)
+		buf.WriteString("\n")
+	case FormatK8sSecret:
+		// a secret for each environment, so a deployment can refer to the same variables whichever it uses
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteString("---\n")
+			}
+			fmt.Fprintf(&buf, "apiVersion: v1\nkind: Secret\nmetadata:\n  name: %s\ntype: Opaque\nstringData:\n", SecretName(k.Environment))
+			for _, v := range k.variables(false) {
+				value, _ := json.Marshal(v.value)
+				fmt.Fprintf(&buf, "  %s: %s\n", v.name, value)
+			}
+		}
+	default:
+		return "", fmt.Errorf(`unknown format "%s", expected one of %s`, format, strings.Join(Formats, ", "))
+	}
+	return buf.String(), nil
+}
+
+// SecretName returns the name of the kubernetes secret for an environment, which must be a valid dns label
+func SecretName(environment string) string {
+	return "launchdarkly-" + strings.Trim(nonNameChars.ReplaceAllString(strings.ToLower(environment), "-"), "-")
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,74 @@
+package sdkkeys
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	production = Keys{Environment: "production", SDKKey: "sdk-1234-abcd-5678", MobileKey: "mob-1234-abcd-5678", ClientSideID: "5c1a"}
+	staging    = Keys{Environment: "my.staging", SDKKey: "sdk-9", MobileKey: "mob-9", ClientSideID: "5c1b"}
+)
+
+func TestMask(t *testing.T) {
+	assert.Equal(t, "sdk-**********5678", Mask("sdk-1234-abcd-5678"))
+	assert.Equal(t, "*****", Mask("sdk-9"))
+	assert.Equal(t, "", Mask(""))
+	assert.Equal(t, Keys{Environment: "production", SDKKey: "sdk-**********5678", MobileKey: "mob-**********5678",
+		ClientSideID: "5c1a"}, production.Masked())
+}
+
+func TestFormatOne(t *testing.T) {
+	for format, expected := range map[string]string{
+		FormatDotenv: "LD_SDK_KEY=sdk-1234-abcd-5678\nLD_MOBILE_KEY=mob-1234-abcd-5678\nLD_CLIENT_SIDE_ID=5c1a\n",
+		FormatShell:  "export LD_SDK_KEY='sdk-1234-abcd-5678'\nexport LD_MOBILE_KEY='mob-1234-abcd-5678'\nexport LD_CLIENT_SIDE_ID='5c1a'\n",
+		FormatJSON:   "{\n  \"sdkKey\": \"sdk-1234-abcd-5678\",\n  \"mobileKey\": \"mob-1234-abcd-5678\",\n  \"clientSideId\": \"5c1a\"\n}\n",
+		FormatK8sSecret: `apiVersion: v1
+kind: Secret
+metadata:
+  name: launchdarkly-production
+type: Opaque
+stringData:
+  LD_SDK_KEY: "sdk-1234-abcd-5678"
+  LD_MOBILE_KEY: "mob-1234-abcd-5678"
+  LD_CLIENT_SIDE_ID: "5c1a"
+`,
+	} {
+		out, err := Format([]Keys{production}, format)
+		require.NoError(t, err)
+		assert.Equal(t, expected, out, format)
+	}
+}
+
+func TestFormatMany(t *testing.T) {
+	out, err := Format([]Keys{production, staging}, FormatDotenv)
+	require.NoError(t, err)
+	assert.Equal(t, `# production
+LD_PRODUCTION_SDK_KEY=sdk-1234-abcd-5678
+LD_PRODUCTION_MOBILE_KEY=mob-1234-abcd-5678
+LD_PRODUCTION_CLIENT_SIDE_ID=5c1a
+
+# my.staging
+LD_MY_STAGING_SDK_KEY=sdk-9
+LD_MY_STAGING_MOBILE_KEY=mob-9
+LD_MY_STAGING_CLIENT_SIDE_ID=5c1b
+`, out)
+
+	out, err = Format([]Keys{production, staging}, FormatJSON)
+	require.NoError(t, err)
+	assert.Contains(t, out, `"my.staging": {`)
+
+	out, err = Format([]Keys{production, staging}, FormatK8sSecret)
+	require.NoError(t, err)
+	assert.Contains(t, out, "---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: launchdarkly-my-staging\n")
+	assert.Contains(t, out, `  LD_SDK_KEY: "sdk-9"`)
+
+	_, err = Format([]Keys{production}, "xml")
+	assert.Error(t, err)
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, `'it'\''s'`, shellQuote("it's"))
+}
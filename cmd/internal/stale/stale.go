@@ -0,0 +1,82 @@
+// Package stale classifies flags by their status in each environment and ranks the ones that may be cleaned up
+package stale
+
+// Flag statuses as reported by the api
+const (
+	StatusNew      = "new"
+	StatusActive   = "active"
+	StatusInactive = "inactive"
+	StatusLaunched = "launched"
+	// StatusMixed is used when a flag's status differs between environments
+	StatusMixed = "mixed"
+	// StatusUnknown is used when the api has no status for a flag in any environment
+	StatusUnknown = "unknown"
+)
+
+// Flag holds what the ranking of a flag depends on
+type Flag struct {
+	// Statuses maps environment keys to the status of the flag in each environment that has one
+	Statuses   map[string]string
+	Temporary  bool
+	References *int
+	AgeDays    int
+}
+
+// Classify returns the status shared by every environment, "mixed" if the environments disagree or "unknown" if no
+// environment has a status
+func Classify(statuses map[string]string) string {
+	status := ""
+	for _, s := range statuses {
+		if status != "" && s != status {
+			return StatusMixed
+		}
+		status = s
+	}
+	if status == "" {
+		return StatusUnknown
+	}
+	return status
+}
+
+// Score ranks cleanup candidates.  Flags that are launched or inactive in every environment are candidates, and so
+// are flags that are launched in some environments and inactive in the others.  A flag without a status in any
+// environment is never a candidate, since nothing is known about its use.  Points are added for each launched or
+// inactive environment, for temporary flags, for flags without references in source code and for every month of
+// age, and removed for each environment in which the flag is new or active.
+func Score(f Flag) (candidate bool, score int) {
+	for _, s := range f.Statuses {
+		switch s {
+		case StatusLaunched:
+			score += 3
+		case StatusInactive:
+			score += 2
+		case StatusNew, StatusActive:
+			score -= 2
+		}
+	}
+
+	status := Classify(f.Statuses)
+	candidate = status == StatusLaunched || status == StatusInactive
+	if status == StatusMixed {
+		candidate = true
+		for _, s := range f.Statuses {
+			if s == StatusNew || s == StatusActive {
+				candidate = false
+			}
+		}
+	}
+	if candidate {
+		score += 5
+	}
+	if f.Temporary {
+		score += 2
+	}
+	if f.References != nil && *f.References == 0 {
+		score += 3
+	}
+	months := f.AgeDays / 30
+	if months > 6 {
+		months = 6
+	}
+	return candidate, score + months
+}
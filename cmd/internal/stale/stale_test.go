@@ -0,0 +1,50 @@
+package stale
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	specs := []struct {
+		name      string
+		statuses  map[string]string
+		status    string
+		candidate bool
+	}{
+		{"no environments", nil, StatusUnknown, false},
+		{"no status data", map[string]string{}, StatusUnknown, false},
+		{"launched everywhere", map[string]string{"production": StatusLaunched, "test": StatusLaunched}, StatusLaunched, true},
+		{"inactive everywhere", map[string]string{"production": StatusInactive, "test": StatusInactive}, StatusInactive, true},
+		{"active everywhere", map[string]string{"production": StatusActive}, StatusActive, false},
+		{"new everywhere", map[string]string{"production": StatusNew}, StatusNew, false},
+		{"launched and inactive", map[string]string{"production": StatusLaunched, "test": StatusInactive}, StatusMixed, true},
+		{"launched and active", map[string]string{"production": StatusLaunched, "test": StatusActive}, StatusMixed, false},
+		{"inactive and new", map[string]string{"production": StatusInactive, "test": StatusNew}, StatusMixed, false},
+	}
+	for _, tt := range specs {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.status, Classify(tt.statuses))
+			candidate, _ := Score(Flag{Statuses: tt.statuses})
+			assert.Equal(t, tt.candidate, candidate)
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	zero, one := 0, 1
+	launched := map[string]string{"production": StatusLaunched, "test": StatusLaunched}
+
+	_, score := Score(Flag{Statuses: launched})
+	assert.Equal(t, 3+3+5, score)
+
+	_, score = Score(Flag{Statuses: launched, Temporary: true, References: &zero, AgeDays: 400})
+	assert.Equal(t, 3+3+5+2+3+6, score)
+
+	_, score = Score(Flag{Statuses: launched, References: &one, AgeDays: 65})
+	assert.Equal(t, 3+3+5+2, score)
+
+	_, score = Score(Flag{Statuses: map[string]string{"production": StatusActive, "test": StatusNew}, Temporary: true})
+	assert.Equal(t, -2-2+2, score)
+}
@@ -0,0 +1,151 @@
+// Package stats compares the conversion rates of the variations of an experiment with a control variation
+package stats
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultLevel is the confidence level of intervals unless another is given
+const DefaultLevel = 0.95
+
+// Sample counts the users who saw a variation and how many of them converted
+type Sample struct {
+	Name        string
+	Conversions int
+	Impressions int
+}
+
+// Interval is a confidence interval
+type Interval struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// Result describes a variation and compares it with the control.  The comparison is left out for the control, and
+// when the control has no conversions or there are too few users to compare them.
+type Result struct {
+	Variation      string    `json:"variation"`
+	Control        bool      `json:"control"`
+	Conversions    int       `json:"conversions"`
+	Impressions    int       `json:"impressions"`
+	ConversionRate float64   `json:"conversionRate"`
+	RateInterval   Interval  `json:"conversionRateInterval"`
+	Lift           *float64  `json:"lift,omitempty"`
+	LiftInterval   *Interval `json:"liftInterval,omitempty"`
+	ZScore         *float64  `json:"zScore,omitempty"`
+	// Confidence is the probability that the difference from the control is not chance, i.e. 1 - p
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+// Compare compares each sample with the sample at index control.  Intervals are for the confidence level, such as
+// 0.95.  The rates and intervals use the normal approximation, which holds for the numbers of users in most
+// experiments.
+func Compare(samples []Sample, control int, level float64) ([]Result, error) {
+	if control < 0 || control >= len(samples) {
+		return nil, fmt.Errorf("control variation %d does not exist", control)
+	}
+	if level <= 0 || level >= 1 {
+		return nil, fmt.Errorf("confidence level %v must be between 0 and 1", level)
+	}
+	z := quantile((1 + level) / 2)
+
+	controlRate, controlErr := rate(samples[control])
+	results := make([]Result, len(samples))
+	for i, s := range samples {
+		r, stdErr := rate(s)
+		results[i] = Result{
+			Variation:      s.Name,
+			Control:        i == control,
+			Conversions:    s.Conversions,
+			Impressions:    s.Impressions,
+			ConversionRate: r,
+			RateInterval:   Interval{Low: r - z*stdErr, High: r + z*stdErr},
+		}
+		if i == control || controlRate == 0 || s.Impressions == 0 {
+			continue
+		}
+		diff := r - controlRate
+		diffErr := math.Sqrt(stdErr*stdErr + controlErr*controlErr)
+		lift := diff / controlRate
+		results[i].Lift = &lift
+		results[i].LiftInterval = &Interval{Low: (diff - z*diffErr) / controlRate, High: (diff + z*diffErr) / controlRate}
+		if diffErr > 0 {
+			zScore := diff / diffErr
+			confidence := math.Erf(math.Abs(zScore) / math.Sqrt2)
+			results[i].ZScore = &zScore
+			results[i].Confidence = &confidence
+		}
+	}
+	return results, nil
+}
+
+// rate returns the conversion rate of a sample and its standard error
+func rate(s Sample) (float64, float64) {
+	if s.Impressions <= 0 {
+		return 0, 0
+	}
+	p := float64(s.Conversions) / float64(s.Impressions)
+	return p, math.Sqrt(p * (1 - p) / float64(s.Impressions))
+}
+
+// ParseFraction parses a fraction such as 0.05, or a percentage such as 5%
+func ParseFraction(s string) (float64, error) {
+	trimmed := strings.TrimSpace(s)
+	percent := strings.HasSuffix(trimmed, "%")
+	f, err := strconv.ParseFloat(strings.TrimSuffix(trimmed, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf(`invalid fraction or percentage "%s"`, s)
+	}
+	if percent {
+		return f / 100, nil
+	}
+	return f, nil
+}
+
+// SampleSize returns the impressions each variation needs for a two-sided test at significance level alpha to have
+// the given power to detect a relative lift in a baseline conversion rate
+func SampleSize(baseline, lift, alpha, power float64) (int, error) {
+	if baseline <= 0 || baseline >= 1 {
+		return 0, fmt.Errorf("baseline conversion rate %v must be between 0 and 1", baseline)
+	}
+	expected := baseline * (1 + lift)
+	if lift == 0 || expected <= 0 || expected >= 1 {
+		return 0, fmt.Errorf("a lift of %v from %v is not a conversion rate between 0 and 1", lift, baseline)
+	}
+	if alpha <= 0 || alpha >= 1 {
+		return 0, fmt.Errorf("alpha %v must be between 0 and 1", alpha)
+	}
+	if power <= 0 || power >= 1 {
+		return 0, fmt.Errorf("power %v must be between 0 and 1", power)
+	}
+	zAlpha := quantile(1 - alpha/2)
+	zPower := quantile(power)
+	mean := (baseline + expected) / 2
+	n := zAlpha*math.Sqrt(2*mean*(1-mean)) + zPower*math.Sqrt(baseline*(1-baseline)+expected*(1-expected))
+	diff := expected - baseline
+	return int(math.Ceil(n * n / (diff * diff))), nil
+}
+
+// PValue returns the two-sided p-value of a z-score
+func PValue(zScore float64) float64 {
+	return math.Erfc(math.Abs(zScore) / math.Sqrt2)
+}
+
+// ImpressionsToSignificance estimates the impressions a variation needs before its difference from the control is
+// significant at level alpha, if the conversion rates stay as they are.  It returns false if there is no difference.
+func ImpressionsToSignificance(r Result, alpha float64) (int, bool) {
+	if r.ZScore == nil || *r.ZScore == 0 {
+		return 0, false
+	}
+	// the z-score grows with the square root of the number of impressions
+	ratio := quantile(1-alpha/2) / math.Abs(*r.ZScore)
+	return int(math.Ceil(float64(r.Impressions) * ratio * ratio)), true
+}
+
+// quantile returns the value below which a fraction p of the standard normal distribution lies
+func quantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
@@ -0,0 +1,97 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	results, err := Compare([]Sample{
+		{Name: "control", Conversions: 40, Impressions: 1000},
+		{Name: "blue", Conversions: 55, Impressions: 1000},
+		{Name: "green", Conversions: 0, Impressions: 0},
+	}, 0, DefaultLevel)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	control := results[0]
+	assert.True(t, control.Control)
+	assert.InDelta(t, 0.04, control.ConversionRate, 1e-9)
+	assert.InDelta(t, 0.0279, control.RateInterval.Low, 1e-4)
+	assert.InDelta(t, 0.0521, control.RateInterval.High, 1e-4)
+	assert.Nil(t, control.Lift)
+
+	blue := results[1]
+	require.NotNil(t, blue.Lift)
+	assert.InDelta(t, 0.375, *blue.Lift, 1e-9)
+	assert.InDelta(t, -0.0908, blue.LiftInterval.Low, 1e-3)
+	assert.InDelta(t, 0.8408, blue.LiftInterval.High, 1e-3)
+	assert.InDelta(t, 1.5779, *blue.ZScore, 1e-3)
+	assert.InDelta(t, 0.8847, *blue.Confidence, 1e-3)
+
+	assert.Nil(t, results[2].Lift, "no impressions")
+}
+
+func TestCompareControl(t *testing.T) {
+	samples := []Sample{{Name: "a", Conversions: 10, Impressions: 100}, {Name: "b", Conversions: 5, Impressions: 100}}
+	results, err := Compare(samples, 1, 0.9)
+	require.NoError(t, err)
+	assert.True(t, results[1].Control)
+	assert.InDelta(t, 1.0, *results[0].Lift, 1e-9)
+
+	_, err = Compare(samples, 2, 0.9)
+	assert.Error(t, err)
+	_, err = Compare(samples, 0, 95)
+	assert.Error(t, err)
+}
+
+func TestCompareNoControlConversions(t *testing.T) {
+	results, err := Compare([]Sample{{Name: "a", Impressions: 100}, {Name: "b", Conversions: 5, Impressions: 100}}, 0, DefaultLevel)
+	require.NoError(t, err)
+	assert.Nil(t, results[1].Lift)
+}
+
+func TestSampleSize(t *testing.T) {
+	n, err := SampleSize(0.04, 0.05, 0.05, 0.8)
+	require.NoError(t, err)
+	// the usual two-proportion estimate for 4% vs 4.2%
+	assert.InDelta(t, 154000, n, 1000)
+
+	smaller, err := SampleSize(0.04, 0.5, 0.05, 0.8)
+	require.NoError(t, err)
+	assert.True(t, smaller < n, "larger lifts need fewer impressions")
+
+	for _, args := range [][]float64{{0, 0.05, 0.05, 0.8}, {0.04, 0, 0.05, 0.8}, {0.04, 0.05, 1, 0.8}, {0.04, 0.05, 0.05, 0}, {0.8, 0.5, 0.05, 0.8}} {
+		_, err := SampleSize(args[0], args[1], args[2], args[3])
+		assert.Error(t, err, "%v", args)
+	}
+}
+
+func TestPValue(t *testing.T) {
+	assert.InDelta(t, 0.05, PValue(1.96), 1e-3)
+	assert.InDelta(t, 0.05, PValue(-1.96), 1e-3)
+	assert.InDelta(t, 1, PValue(0), 1e-9)
+}
+
+func TestImpressionsToSignificance(t *testing.T) {
+	zScore := 0.98
+	n, ok := ImpressionsToSignificance(Result{Impressions: 1000, ZScore: &zScore}, 0.05)
+	assert.True(t, ok)
+	assert.InDelta(t, 4000, n, 10)
+
+	_, ok = ImpressionsToSignificance(Result{Impressions: 1000}, 0.05)
+	assert.False(t, ok)
+}
+
+func TestParseFraction(t *testing.T) {
+	f, err := ParseFraction("5%")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.05, f, 1e-12)
+	f, err = ParseFraction(" 0.04 ")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.04, f, 1e-12)
+	_, err = ParseFraction("five")
+	assert.Error(t, err)
+}
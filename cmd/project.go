@@ -3,10 +3,12 @@ package cmd
 import (
 	"bytes"
 	"errors"
+	"strings"
 
 	"github.com/launchdarkly/ldc/cmd/internal/path"
 
 	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/pflag"
 	ishell "gopkg.in/abiosoft/ishell.v2"
 
 	ldapi "github.com/launchdarkly/api-client-go"
@@ -15,17 +17,17 @@ import (
 )
 
 func addProjectCommands(shell *ishell.Shell) {
-	root := &ishell.Cmd{
+	root := withFlags(&ishell.Cmd{
 		Name:    "projects",
 		Aliases: []string{"project"},
 		Help:    "list and operate on projects",
 		Func:    showProjects,
-	}
-	root.AddCmd(&ishell.Cmd{
+	}, listProjectsFlagSet)
+	root.AddCmd(withFlags(&ishell.Cmd{
 		Name: "list",
-		Help: "list projects",
+		Help: "list projects: project list [--tag tag]",
 		Func: showProjects,
-	})
+	}, listProjectsFlagSet))
 	root.AddCmd(&ishell.Cmd{
 		Name:      "show",
 		Help:      "show project",
@@ -38,6 +40,25 @@ func addProjectCommands(shell *ishell.Shell) {
 		Help:    "create a project: project create key [name]",
 		Func:    createProject,
 	})
+	root.AddCmd(withFlags(&ishell.Cmd{
+		Name:      "clone",
+		Aliases:   []string{"copy"},
+		Help:      "copy a project with its environments and flags: project clone source destination",
+		Completer: projectCompleter,
+		Func:      cloneProject,
+	}, cloneFlagSet))
+	root.AddCmd(withFlags(&ishell.Cmd{
+		Name:      "edit",
+		Help:      "edit a project as json or yaml: project edit key",
+		Completer: projectCompleter,
+		Func:      editProject,
+	}, editFlagSet))
+	root.AddCmd(withFlags(&ishell.Cmd{
+		Name:      "set",
+		Help:      "change settings of a project: project set key --name name --add-tag tag --include-in-snippet",
+		Completer: projectCompleter,
+		Func:      setProject,
+	}, setProjectFlagSet))
 	root.AddCmd(&ishell.Cmd{
 		Name:      "delete",
 		Aliases:   []string{"remove"},
@@ -58,16 +79,21 @@ func (f projPath) Key() string {
 }
 
 func listProjects(configKey *string) ([]ldapi.Project, error) {
-	client, err := api.GetClient(getServer(configKey))
-	if err != nil {
-		return nil, err
-	}
-	auth := api.GetAuthCtx(getToken(configKey))
-	projects, _, err := client.ProjectsApi.GetProjects(auth)
+	var projects []ldapi.Project
+	err := cached(configKey, "projects", &projects, func() error {
+		client, err := api.GetClient(getServer(configKey))
+		if err != nil {
+			return err
+		}
+		auth := api.GetAuthCtx(getToken(configKey))
+		result, _, err := client.ProjectsApi.GetProjects(auth)
+		projects = result.Items
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	return projects.Items, nil
+	return projects, nil
 }
 
 func listProjectKeys(configKey *string) (keys []string, err error) {
@@ -85,7 +111,6 @@ func showProject(c *ishell.Context) {
 	projPath, proj := getProjectArg(c)
 
 	if proj == nil {
-		c.Println("Project not found")
 		return
 	}
 
@@ -97,12 +122,41 @@ func showProject(c *ishell.Context) {
 	showEnvironmentsForProject(c, projPath)
 }
 
+func listProjectsFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("list", pflag.ContinueOnError)
+	flags.StringArray("tag", nil, "only list projects with this tag, which may be repeated to require several")
+	return flags
+}
+
+func setProjectFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("set", pflag.ContinueOnError)
+	flags.String("name", "", "name of the project")
+	addTagFlags(flags)
+	flags.Bool("include-in-snippet", false, "make new flags available to client-side SDKs by default")
+	return flags
+}
+
 func showProjects(c *ishell.Context) {
-	projects, err := listProjects(currentConfig)
+	flags := listProjectsFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	if len(c.Args) > 0 {
+		reportError(c, errTooManyArgs)
+		return
+	}
+	tags, _ := flags.GetStringArray("tag")
+	allProjects, err := listProjects(currentConfig)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
+	projects := []ldapi.Project{}
+	for _, project := range allProjects {
+		if hasTags(project.Tags, tags) {
+			projects = append(projects, project)
+		}
+	}
 
 	if renderJSON(c) {
 		printJSON(c, projects)
@@ -111,13 +165,13 @@ func showProjects(c *ishell.Context) {
 
 	buf := bytes.Buffer{}
 	table := tablewriter.NewWriter(&buf)
-	table.SetHeader([]string{"Key", "Name"})
+	table.SetHeader([]string{"Key", "Name", "Tags"})
 	for _, project := range projects {
-		table.Append([]string{project.Key, project.Name})
+		table.Append([]string{project.Key, project.Name, strings.Join(project.Tags, ", ")})
 	}
 	table.Render()
 	if buf.Len() > 1000 {
-		c.Err(c.ShowPaged(buf.String()))
+		reportError(c, c.ShowPaged(buf.String()))
 	} else {
 		c.Print(buf.String())
 	}
@@ -161,18 +215,18 @@ func getProjectArg(c *ishell.Context) (projPath, *ldapi.Project) {
 	if len(c.Args) > 0 {
 		realPath, err = realProjPath(c.Args[0])
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return projPath{}, nil
 		}
 		auth := api.GetAuthCtx(getToken(realPath.Config()))
 		client, err := api.GetClient(getServer(realPath.Config()))
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return projPath{}, nil
 		}
 		proj, _, err := client.ProjectsApi.GetProject(auth, realPath.Key())
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return projPath{}, nil
 		}
 		return realPath, &proj
@@ -180,12 +234,12 @@ func getProjectArg(c *ishell.Context) (projPath, *ldapi.Project) {
 
 	proj, err := chooseProject(c, currentConfig)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return projPath{}, nil
 	}
 	realPath, err = realProjPath(proj.Key)
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return projPath{}, nil
 	}
 	return realPath, proj
@@ -207,9 +261,9 @@ func chooseProject(c *ishell.Context, config *string) (*ldapi.Project, error) {
 	}
 
 	options := keysForProjects(projects)
-	choice := c.MultiChoice(options, "Choose a project")
-	if choice < 0 {
-		return nil, nil
+	choice, err := multiChoice(c, options, "Choose a project")
+	if err != nil {
+		return nil, err
 	}
 
 	return &projects[choice], nil
@@ -220,17 +274,17 @@ func createProject(c *ishell.Context) {
 	var p projPath
 	switch len(c.Args) {
 	case 0:
-		c.Err(errors.New("please supply at least a key for the new environment"))
+		reportError(c, errors.New("please supply at least a key for the new environment"))
 		return
 	case 1, 2:
 		var err error
 		p, err = realProjPath(c.Args[0])
 		if err != nil {
-			c.Err(err)
+			reportError(c, err)
 			return
 		}
 		if p.Depth() != 1 {
-			c.Err(errors.New("invalid path"))
+			reportError(c, errors.New("invalid path"))
 		}
 		if len(c.Args) > 1 {
 			name = c.Args[1]
@@ -238,19 +292,19 @@ func createProject(c *ishell.Context) {
 			name = p.Key()
 		}
 	default:
-		c.Err(errors.New(`expected arguments are "key [name]"`))
+		reportError(c, errors.New(`expected arguments are "key [name]"`))
 		return
 	}
 	// TODO: openapi should be updated to return the new project
 	client, err := api.GetClient(getServer(p.Config()))
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	auth := api.GetAuthCtx(getToken(p.Config()))
 
 	if _, err := client.ProjectsApi.PostProject(auth, ldapi.ProjectBody{Key: p.Key(), Name: name}); err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	if !renderJSON(c) {
@@ -258,7 +312,7 @@ func createProject(c *ishell.Context) {
 	}
 	project, _, err := client.ProjectsApi.GetProject(auth, p.Key())
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	switchToProject(c, p, &project)
@@ -268,10 +322,135 @@ func createProject(c *ishell.Context) {
 	}
 }
 
+// hasTags returns true if tags includes every one of wanted
+func hasTags(tags []string, wanted []string) bool {
+	for _, tag := range wanted {
+		if !containsString(tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// getProjectJSON gets a project with the properties the api client does not support, such as
+// includeInSnippetByDefault.  Its environments are left out because they are changed with the environments commands.
+func getProjectJSON(p projPath) (map[string]interface{}, error) {
+	var project map[string]interface{}
+	if err := api.GetJSON(getServer(p.Config()), getToken(p.Config()), "/projects/"+p.Key(), &project); err != nil {
+		return nil, err
+	}
+	delete(project, "environments")
+	return project, nil
+}
+
+func setProject(c *ishell.Context) {
+	flags := setProjectFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	if len(c.Args) > 1 {
+		reportError(c, errTooManyArgs)
+		return
+	}
+	p, project := getProjectArg(c)
+	if project == nil {
+		return
+	}
+
+	var patch []ldapi.PatchOperation
+	if name, _ := flags.GetString("name"); flags.Changed("name") && name != project.Name {
+		patch = append(patch, ldapi.PatchOperation{Op: "replace", Path: "/name", Value: interfacePtr(name)})
+	}
+	if tags := applyTagFlags(flags, project.Tags); strings.Join(tags, ",") != strings.Join(project.Tags, ",") {
+		patch = append(patch, ldapi.PatchOperation{Op: "replace", Path: "/tags", Value: interfacePtr(tags)})
+	}
+	if flags.Changed("include-in-snippet") {
+		include, _ := flags.GetBool("include-in-snippet")
+		patch = append(patch, ldapi.PatchOperation{Op: "replace", Path: "/includeInSnippetByDefault", Value: interfacePtr(include)})
+	}
+	if len(patch) == 0 {
+		reportError(c, errors.New("nothing to change: use --name, --include-in-snippet or the tag options"))
+		return
+	}
+
+	client, err := api.GetClient(getServer(p.Config()))
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	auth := api.GetAuthCtx(getToken(p.Config()))
+	updated, _, err := client.ProjectsApi.PatchProject(auth, p.Key(), patch)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	if renderJSON(c) {
+		printJSON(c, updated)
+		return
+	}
+	c.Printf("Updated project %s\n", p.Key())
+}
+
+func editProject(c *ishell.Context) {
+	format, ok := parseEditFlags(c)
+	if !ok {
+		return
+	}
+	p, project := getProjectArg(c)
+	if project == nil {
+		return
+	}
+	current, err := getProjectJSON(p)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	data, err := marshalEdit(current)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	client, err := api.GetClient(getServer(p.Config()))
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	auth := api.GetAuthCtx(getToken(p.Config()))
+
+	changed, err := editResource(c, editTarget{
+		kind:       "project",
+		definition: "Project",
+		newValue:   func() interface{} { return &ldapi.Project{} },
+		fetch: func() ([]byte, int, error) {
+			current, err := getProjectJSON(p)
+			if err != nil {
+				return nil, 0, err
+			}
+			data, err := marshalEdit(current)
+			return data, noVersion, err
+		},
+		patch: func(patchComment ldapi.PatchComment) error {
+			_, _, err := client.ProjectsApi.PatchProject(auth, p.Key(), patchComment.Patch)
+			return err
+		},
+		noComment: true,
+	}, format, data, noVersion)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+
+	if !changed {
+		c.Println("No changes")
+		return
+	}
+
+	c.Println("Updated project")
+}
+
 func deleteProject(c *ishell.Context) {
 	projPath, project := getProjectArg(c)
 	if project == nil {
-		c.Err(errNotFound)
 		return
 	}
 	if !confirmDelete(c, "project key", project.Key) {
@@ -279,13 +458,13 @@ func deleteProject(c *ishell.Context) {
 	}
 	client, err := api.GetClient(getServer(projPath.Config()))
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	auth := api.GetAuthCtx(getToken(projPath.Config()))
 	_, err = client.ProjectsApi.DeleteProject(auth, projPath.Key())
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 	if isInteractive(c) {
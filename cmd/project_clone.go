@@ -0,0 +1,426 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/pflag"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+
+	"github.com/launchdarkly/ldc/api"
+	"github.com/launchdarkly/ldc/cmd/internal/clone"
+	"github.com/launchdarkly/ldc/cmd/internal/path"
+	"github.com/launchdarkly/ldc/goalapi"
+)
+
+func cloneFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("clone", pflag.ContinueOnError)
+	flags.Bool("targeting", false, "also copy the targeting of each flag in each environment")
+	flags.Bool("segments", false, "also copy the segments of each environment")
+	flags.Bool("goals", false, "also copy the goals of each environment and attach them to the copied flags")
+	flags.String("name", "", "name of the new project (default its key)")
+	flags.Bool("restart", false, "start again instead of resuming a clone that failed")
+	return flags
+}
+
+// cloneStateFile nests the state of a clone in a directory for each of the source config and project and the
+// destination config, so that keys containing separators cannot make two clones share a file
+func cloneStateFile(src, dst projPath) (string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	config := "default"
+	if src.Config() != nil {
+		config = *src.Config()
+	}
+	dstConfig := config
+	if dst.Config() != nil {
+		dstConfig = *dst.Config()
+	}
+	elems := []string{home, ".config", "ldc", "clones"}
+	for _, name := range []string{config, src.Key(), dstConfig, dst.Key()} {
+		elems = append(elems, url.PathEscape(name))
+	}
+	return filepath.Join(elems...) + ".json", nil
+}
+
+// projectCloner copies a project, recording each step it completes so that a failed copy can be resumed
+type projectCloner struct {
+	src, dst projPath
+	name     string
+	project  *ldapi.Project
+	flags    map[string]ldapi.FeatureFlag
+	// segments are the segments of each environment by key, as returned by the api
+	segments map[string]map[string]map[string]interface{}
+	goals    map[string]goalapi.Goal
+	comment  string
+	state    *clone.State
+}
+
+func cloneProject(c *ishell.Context) {
+	flags := cloneFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	if len(c.Args) != 2 {
+		reportError(c, errors.New(`expected arguments are "source destination"`))
+		return
+	}
+	var options clone.Options
+	options.Targeting, _ = flags.GetBool("targeting")
+	options.Segments, _ = flags.GetBool("segments")
+	options.Goals, _ = flags.GetBool("goals")
+	restart, _ := flags.GetBool("restart")
+	name, _ := flags.GetString("name")
+
+	src, project := getProjectArg(c)
+	if project == nil {
+		return
+	}
+	dst, err := realProjPath(c.Args[1])
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	if src.String() == dst.String() {
+		reportError(c, errors.New("the source and destination are the same project"))
+		return
+	}
+
+	cloner := projectCloner{src: src, dst: dst, name: ifNotBlank(name, dst.Key()), project: project}
+	source, err := cloner.read(options)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	stateFile, err := cloneStateFile(src, dst)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	state, err := clone.Load(stateFile)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	if state == nil || restart {
+		state = &clone.State{Source: src.String(), Destination: dst.String()}
+	}
+	if state.GoalIDs == nil {
+		state.GoalIDs = make(map[string]string)
+	}
+	state.Options = options
+	cloner.state = state
+
+	if options.Targeting || options.Goals {
+		comment, err := getChangeComment(c, dst.Config(), source.Environments, "")
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		cloner.comment = ifNotBlank(comment, fmt.Sprintf("Cloned from project %s", src.Key()))
+		if err := confirmChange(c, dst.Config(), source.Environments); err != nil {
+			reportError(c, err)
+			return
+		}
+	}
+
+	steps := clone.Plan(source, options)
+	done := 0
+	for _, step := range steps {
+		if state.IsDone(step) {
+			done++
+		}
+	}
+	if done > 0 && !renderJSON(c) {
+		c.Printf("Resuming the clone of %s to %s: %d of %d steps are done\n", src.Key(), dst.Key(), done, len(steps))
+	}
+	for i, step := range steps {
+		if state.IsDone(step) {
+			continue
+		}
+		description, err := cloner.run(step)
+		if err != nil && !errors.Is(err, api.ErrDryRun) {
+			if !renderJSON(c) {
+				c.Printf("Stopped at step %d of %d, copying %s.  Run the same command again to resume.\n", i+1, len(steps), step.ID())
+			}
+			reportError(c, err)
+			return
+		}
+		// nothing is changed in a dry run, so there is nothing to resume
+		if !api.DryRun {
+			state.Complete(step)
+			if err := state.Save(stateFile); err != nil {
+				reportError(c, err)
+				return
+			}
+		}
+		if !renderJSON(c) {
+			c.Printf("[%d/%d] %s\n", i+1, len(steps), description)
+		}
+	}
+	if !api.DryRun {
+		if err := os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
+			reportError(c, err)
+			return
+		}
+	}
+
+	if renderJSON(c) {
+		printJSON(c, state)
+		return
+	}
+	c.Printf("Cloned project %s to %s\n", src.Key(), dst.Key())
+}
+
+// read gets what is to be copied from the source project
+func (pc *projectCloner) read(options clone.Options) (clone.Source, error) {
+	var source clone.Source
+	for _, env := range pc.project.Environments {
+		source.Environments = append(source.Environments, env.Key)
+	}
+
+	flags, err := listFlags(pc.src.Config(), pc.src.Key())
+	if err != nil {
+		return source, err
+	}
+	pc.flags = make(map[string]ldapi.FeatureFlag)
+	for _, flag := range flags {
+		source.Flags = append(source.Flags, flag.Key)
+		if len(flag.GoalIds) > 0 {
+			source.GoalFlags = append(source.GoalFlags, flag.Key)
+		}
+		pc.flags[flag.Key] = flag
+	}
+
+	if options.Segments {
+		source.Segments = make(map[string][]string)
+		pc.segments = make(map[string]map[string]map[string]interface{})
+		for _, env := range source.Environments {
+			var segments struct {
+				Items []map[string]interface{} `json:"items"`
+			}
+			err := api.GetJSON(getServer(pc.src.Config()), getToken(pc.src.Config()),
+				fmt.Sprintf("/segments/%s/%s", pc.src.Key(), env), &segments)
+			if err != nil {
+				return source, err
+			}
+			pc.segments[env] = make(map[string]map[string]interface{})
+			for _, segment := range segments.Items {
+				key, _ := segment["key"].(string)
+				source.Segments[env] = append(source.Segments[env], key)
+				pc.segments[env][key] = segment
+			}
+		}
+	}
+
+	if options.Goals {
+		source.Goals = make(map[string][]string)
+		pc.goals = make(map[string]goalapi.Goal)
+		for _, env := range source.Environments {
+			envPath := pc.envPath(pc.src, env)
+			client, err := newGoalClient(envPath)
+			if err != nil {
+				return source, err
+			}
+			goals, err := listGoals(envPath, client)
+			if err != nil {
+				return source, err
+			}
+			for _, goal := range goals {
+				source.Goals[env] = append(source.Goals[env], goal.ID)
+				pc.goals[goal.ID] = goal
+			}
+		}
+	}
+	return source, nil
+}
+
+func (pc *projectCloner) envPath(p projPath, env string) perProjectPath {
+	return perProjectPath{path.NewAbsPath(p.Config(), p.Key(), env)}
+}
+
+func (pc *projectCloner) environment(key string) ldapi.Environment {
+	for _, env := range pc.project.Environments {
+		if env.Key == key {
+			return env
+		}
+	}
+	return ldapi.Environment{Key: key}
+}
+
+// run copies one step, returning what it did
+func (pc *projectCloner) run(step clone.Step) (string, error) {
+	client, err := api.GetClient(getServer(pc.dst.Config()))
+	if err != nil {
+		return "", err
+	}
+	auth := api.GetAuthCtx(getToken(pc.dst.Config()))
+
+	switch step.Kind {
+	case clone.KindProject:
+		// the environments are created with the project so that it does not get the default ones
+		body := ldapi.ProjectBody{Key: pc.dst.Key(), Name: pc.name}
+		for _, env := range pc.project.Environments {
+			body.Environments = append(body.Environments,
+				ldapi.EnvironmentPost{Key: env.Key, Name: env.Name, Color: env.Color, DefaultTtl: env.DefaultTtl})
+		}
+		resp, err := client.ProjectsApi.PostProject(auth, body)
+		if alreadyExists(resp, err) {
+			// the project may have been created by an earlier attempt whose response was lost, but another project
+			// with the same key must not be cloned into
+			existing, _, getErr := client.ProjectsApi.GetProject(auth, pc.dst.Key())
+			if getErr != nil {
+				return "", getErr
+			}
+			if !pc.isCopy(existing) {
+				return "", fmt.Errorf("project %s already exists", pc.dst.Key())
+			}
+			err = nil
+		}
+		return fmt.Sprintf("Created project %s with %d environments", pc.dst.Key(), len(body.Environments)), err
+
+	case clone.KindEnvironment:
+		env := pc.environment(step.Key)
+		patch := []ldapi.PatchOperation{
+			{Op: "replace", Path: "/secureMode", Value: interfacePtr(env.SecureMode)},
+			{Op: "replace", Path: "/defaultTrackEvents", Value: interfacePtr(env.DefaultTrackEvents)},
+		}
+		if len(env.Tags) > 0 {
+			patch = append(patch, ldapi.PatchOperation{Op: "replace", Path: "/tags", Value: interfacePtr(env.Tags)})
+		}
+		_, _, err := client.EnvironmentsApi.PatchEnvironment(auth, pc.dst.Key(), env.Key, patch)
+		return fmt.Sprintf("Copied the settings of environment %s", env.Key), err
+
+	case clone.KindSegment:
+		segment := pc.segments[step.Environment][step.Key]
+		body := ldapi.UserSegmentBody{Key: step.Key}
+		body.Name, _ = segment["name"].(string)
+		body.Description, _ = segment["description"].(string)
+		if tags, ok := segment["tags"].([]interface{}); ok {
+			for _, tag := range tags {
+				body.Tags = append(body.Tags, fmt.Sprint(tag))
+			}
+		}
+		// a segment that exists was created by an earlier attempt that failed to copy its rules
+		resp, err := client.UserSegmentsApi.PostUserSegment(auth, pc.dst.Key(), step.Environment, body)
+		if err != nil && !alreadyExists(resp, err) && !errors.Is(err, api.ErrDryRun) {
+			return "", err
+		}
+		err = nil
+		if patch := clone.SegmentPatch(segment); len(patch) > 0 {
+			_, _, err = client.UserSegmentsApi.PatchUserSegment(auth, pc.dst.Key(), step.Environment, step.Key, patch)
+		}
+		return fmt.Sprintf("Copied segment %s in %s", step.Key, step.Environment), err
+
+	case clone.KindGoal:
+		goal := pc.goals[step.Key]
+		description := fmt.Sprintf("Copied goal %s in %s", goal.Name, step.Environment)
+		if api.DryRun {
+			// the destination environment, whose key the goal api needs, was not created
+			return description + " (not shown in a dry run)", nil
+		}
+		goalClient, err := newGoalClient(pc.envPath(pc.dst, step.Environment))
+		if err != nil {
+			return "", err
+		}
+		// a goal created by an earlier attempt is reused, as creating it again would make a duplicate
+		existing, err := goalClient.GetGoals(context.Background())
+		if err != nil {
+			return "", err
+		}
+		if id, ok := clone.FindGoal(existing, goal); ok {
+			pc.state.GoalIDs[goal.ID] = id
+			return description, nil
+		}
+		newGoal, err := goalClient.CreateGoal(context.Background(), goalapi.Goal{
+			Name:        goal.Name,
+			Description: goal.Description,
+			Kind:        goal.Kind,
+			Key:         goal.Key,
+			Selector:    goal.Selector,
+			URLs:        goal.URLs,
+		})
+		if err != nil {
+			return "", err
+		}
+		pc.state.GoalIDs[goal.ID] = newGoal.ID
+		return description, nil
+
+	case clone.KindFlag:
+		flag := pc.flags[step.Key]
+		_, resp, err := client.FeatureFlagsApi.PostFeatureFlag(auth, pc.dst.Key(), ldapi.FeatureFlagBody{
+			Name:             flag.Name,
+			Key:              flag.Key,
+			Description:      flag.Description,
+			Variations:       flag.Variations,
+			Temporary:        flag.Temporary,
+			Tags:             flag.Tags,
+			IncludeInSnippet: flag.IncludeInSnippet,
+		}, nil)
+		if alreadyExists(resp, err) {
+			err = nil
+		}
+		return fmt.Sprintf("Created flag %s", flag.Key), err
+
+	case clone.KindTargeting:
+		var flag struct {
+			Environments map[string]map[string]interface{} `json:"environments"`
+		}
+		err := api.GetJSON(getServer(pc.src.Config()), getToken(pc.src.Config()),
+			fmt.Sprintf("/flags/%s/%s", pc.src.Key(), step.Key), &flag)
+		if err != nil {
+			return "", err
+		}
+		description := fmt.Sprintf("Copied the targeting of %s in %s", step.Key, step.Environment)
+		patch := clone.TargetingPatch(step.Environment, flag.Environments[step.Environment])
+		if len(patch) == 0 {
+			return description, nil
+		}
+		_, _, err = sendFlagPatch(pc.dst.Config(), pc.dst.Key(), step.Key, ldapi.PatchComment{Comment: pc.comment, Patch: patch})
+		return description, err
+
+	case clone.KindFlagGoals:
+		goalIDs := clone.RemapGoalIDs(pc.flags[step.Key].GoalIds, pc.state.GoalIDs)
+		description := fmt.Sprintf("Attached %d goals to %s", len(goalIDs), step.Key)
+		if len(goalIDs) == 0 {
+			return description, nil
+		}
+		patch := []ldapi.PatchOperation{{Op: "replace", Path: "/goalIds", Value: interfacePtr(goalIDs)}}
+		_, _, err := sendFlagPatch(pc.dst.Config(), pc.dst.Key(), step.Key, ldapi.PatchComment{Comment: pc.comment, Patch: patch})
+		return description, err
+	}
+	return "", fmt.Errorf("unknown step %s", step.ID())
+}
+
+// isCopy returns true if a project in the destination has the name and environments the clone gives it
+func (pc *projectCloner) isCopy(project ldapi.Project) bool {
+	if project.Name != pc.name || len(project.Environments) != len(pc.project.Environments) {
+		return false
+	}
+	keys := make(map[string]bool)
+	for _, env := range pc.project.Environments {
+		keys[env.Key] = true
+	}
+	for _, env := range project.Environments {
+		if !keys[env.Key] {
+			return false
+		}
+	}
+	return true
+}
+
+// alreadyExists returns true if a create failed because the resource exists, which happens when a step is retried
+// after it made a change and then failed
+func alreadyExists(resp *http.Response, err error) bool {
+	return err != nil && resp != nil && resp.StatusCode == http.StatusConflict
+}
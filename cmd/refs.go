@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	"github.com/launchdarkly/ldc/cmd/internal/refs"
+)
+
+func addRefsCommands(shell *ishell.Shell) {
+	shell.AddCmd(withFlags(&ishell.Cmd{
+		Name: "refs",
+		Help: "find flag references in source code: refs [dir] [--project project] [--delimiters chars] [--pattern ext=regex] [--format table|csv|markdown] [--fail]",
+		Func: showRefs,
+	}, refsFlagSet))
+}
+
+func refsFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("refs", pflag.ContinueOnError)
+	flags.String("project", "", "project whose flags to look for")
+	flags.String("delimiters", refs.DefaultDelimiters, "characters that may surround a flag key")
+	flags.StringArray("pattern", nil, "additional sdk call pattern for a file extension, e.g. .go=Variation\\(\"([^\"]+)\"")
+	flags.String("format", formatTable, "output format: "+strings.Join(reportFormats, ", "))
+	flags.Bool("fail", false, "fail if code references flags that do not exist")
+	return flags
+}
+
+func showRefs(c *ishell.Context) {
+	flags := refsFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	project, _ := flags.GetString("project")
+	delimiters, _ := flags.GetString("delimiters")
+	extraPatterns, _ := flags.GetStringArray("pattern")
+	format, _ := flags.GetString("format")
+	failOnUnknown, _ := flags.GetBool("fail")
+
+	dir := "."
+	if len(c.Args) > 1 {
+		reportError(c, errTooManyArgs)
+		return
+	}
+	if len(c.Args) == 1 {
+		dir = c.Args[0]
+	}
+
+	result, err := scanRefs(dir, project, delimiters, extraPatterns)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+
+	if renderJSON(c) {
+		printJSON(c, result)
+	} else {
+		if err := renderRefs(c, format, result); err != nil {
+			reportError(c, err)
+			return
+		}
+	}
+
+	if failOnUnknown && len(result.Unknown) > 0 {
+		reportError(c, fmt.Errorf("found %d references to unknown flags", len(result.Unknown)))
+	}
+}
+
+// scanRefs scans dir for the flags of a project
+func scanRefs(dir string, project string, delimiters string, extraPatterns []string) (*refs.Result, error) {
+	configKey := currentConfig
+	projectKey := currentProject
+	if project != "" {
+		realPath, err := realProjPath(project)
+		if err != nil {
+			return nil, err
+		}
+		configKey = realPath.Config()
+		projectKey = realPath.Key()
+	}
+
+	keys, err := listFlagKeys(configKey, projectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := make(map[string][]string)
+	for ext, p := range refs.DefaultPatterns {
+		patterns[ext] = append(patterns[ext], p...)
+	}
+	for _, p := range extraPatterns {
+		parts := strings.SplitN(p, "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], ".") {
+			return nil, errors.New(`patterns must have the form ".ext=regex"`)
+		}
+		patterns[parts[0]] = append(patterns[parts[0]], parts[1])
+	}
+
+	scanner, err := refs.NewScanner(keys, delimiters, patterns)
+	if err != nil {
+		return nil, err
+	}
+	return scanner.Scan(dir)
+}
+
+// renderRefs renders a table for each kind of result.  Csv and markdown are rendered as a single table with a status
+// column instead, so that the output can be read by other tools.
+func renderRefs(c *ishell.Context, format string, result *refs.Result) error {
+	if format == formatCSV || format == formatMarkdown {
+		var rows [][]string
+		for _, r := range result.References {
+			rows = append(rows, []string{"referenced", r.Key, r.File, strconv.Itoa(r.Line), r.Context})
+		}
+		for _, r := range result.Unknown {
+			rows = append(rows, []string{"unknown", r.Key, r.File, strconv.Itoa(r.Line), r.Context})
+		}
+		for _, key := range result.Unused {
+			rows = append(rows, []string{"unused", key, "", "", ""})
+		}
+		return renderReport(c, format, []string{"Status", "Key", "File", "Line", "Context"}, rows)
+	}
+
+	header := []string{"Key", "File", "Line", "Context"}
+	toRows := func(references []refs.Reference) (rows [][]string) {
+		for _, r := range references {
+			rows = append(rows, []string{r.Key, r.File, strconv.Itoa(r.Line), r.Context})
+		}
+		return rows
+	}
+
+	c.Println("References:")
+	if err := renderReport(c, format, header, toRows(result.References)); err != nil {
+		return err
+	}
+	if len(result.Unknown) > 0 {
+		c.Println("References to unknown flags:")
+		if err := renderReport(c, format, header, toRows(result.Unknown)); err != nil {
+			return err
+		}
+	}
+	if len(result.Unused) > 0 {
+		c.Println("Flags without references:")
+		var rows [][]string
+		for _, key := range result.Unused {
+			rows = append(rows, []string{key})
+		}
+		if err := renderReport(c, format, []string{"Key"}, rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -25,15 +25,19 @@ var currentEnvironment string
 
 var configFileName string
 
+// commandShell holds every command, whether they are run in the shell or on their own
+var commandShell *ishell.Shell
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:              "ldc",
 	Short:            "ldc is a command-line api client for LaunchDarkly",
 	PersistentPreRun: preRunCmd,
-	Run:              runRootCmd,
+	// Errors are printed by Execute
+	SilenceErrors: true,
 }
 
-// rootCmd represents the base command when called without any subcommands
+// shellCmd starts the interactive shell
 var shellCmd = &cobra.Command{
 	Use:   "shell",
 	Short: "start an interactive shell",
@@ -43,17 +47,10 @@ var shellCmd = &cobra.Command{
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	cmd := rootCmd
-	rootCmdWithShell := *rootCmd
-	rootCmdWithShell.AddCommand(shellCmd)
-	foundCmd, _, err := rootCmdWithShell.Find(os.Args[1:])
-	if err == nil && foundCmd.Use == "shell" {
-		cmd = shellCmd
-	}
-	if err := cmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
+	commandShell = newShell()
+	addCobraCommands(rootCmd, commandShell.Cmds())
+	rootCmd.AddCommand(shellCmd, completionCmd, completeCmd)
+	exitOnError(rootCmd.Execute())
 }
 
 func init() {
@@ -68,7 +65,16 @@ func init() {
 	pflag.String("config-file", "", "Configuration file to use")
 	pflag.Bool("json", false, "Return json")
 	pflag.Bool("debug", false, "Enable debugging")
+	pflag.Bool("yes", false, "Confirm changes to protected environments and edits without asking")
+	pflag.StringP("comment", "m", "", "Comment to record with changes")
+	pflag.Bool("dry-run", false, "Print the requests that would make changes instead of sending them")
+	pflag.Duration("cache-ttl", defaultCacheTTL, "How long to reuse listings of resources, or 0 to always fetch them")
+	pflag.Bool("disk-cache", false, "Share cached listings between commands by storing them in ~/.config/ldc/cache")
+	// help is shown by cobra, but is declared here so parsing the global flags does not stop at it
+	pflag.BoolP("help", "h", false, "Show help")
+	pflag.CommandLine.ParseErrorsWhitelist.UnknownFlags = true
 	pflag.Parse()
+	rootCmd.PersistentFlags().AddFlagSet(pflag.CommandLine)
 
 	viper.AutomaticEnv()
 	viper.SetEnvPrefix("ldc")
@@ -132,6 +138,8 @@ func preRunCmd(cmd *cobra.Command, args []string) {
 	}
 
 	api.Debug = viper.GetBool("debug")
+	api.DryRun = viper.GetBool("dry-run")
+	initCache()
 }
 
 func addTokenCommands(shell *ishell.Shell) {
@@ -140,7 +148,7 @@ func addTokenCommands(shell *ishell.Shell) {
 		Help: "set api key",
 		Func: func(c *ishell.Context) {
 			if len(c.Args) > 1 {
-				c.Err(errors.New("only one argument, the api key, is allowed"))
+				reportError(c, errors.New("only one argument, the api key, is allowed"))
 				return
 			}
 
@@ -148,6 +156,10 @@ func addTokenCommands(shell *ishell.Shell) {
 			if len(c.Args) == 1 {
 				token = c.Args[0]
 			} else {
+				if !canPrompt() {
+					reportError(c, errNoTerminal)
+					return
+				}
 				c.Print("API Key: ")
 				token = c.ReadPassword()
 			}
@@ -159,15 +171,9 @@ func addTokenCommands(shell *ishell.Shell) {
 	shell.AddCmd(root)
 }
 
-func createShell(interactive bool) *ishell.Shell {
+// newShell creates a shell with every command
+func newShell() *ishell.Shell {
 	shell := ishell.New()
-	shell.SetHomeHistoryPath(".ldc_history")
-
-	prompt := fmt.Sprintf("%s/%s> ", currentProject, currentEnvironment)
-	if currentConfig != nil {
-		prompt = fmt.Sprintf(`[%s] %s`, *currentConfig, prompt)
-	}
-	shell.SetPrompt(prompt)
 
 	shell.AddCmd(&ishell.Cmd{
 		Name:    "pwd",
@@ -192,6 +198,7 @@ func createShell(interactive bool) *ishell.Shell {
 		Completer: environmentCompleter,
 		Func: func(c *ishell.Context) {
 			if len(c.Args) > 1 {
+				reportError(c, errTooManyArgs)
 				return
 			}
 			p := path.ResourcePath(firstOrEmpty(c.Args))
@@ -201,12 +208,12 @@ func createShell(interactive bool) *ishell.Shell {
 				auth := api.GetAuthCtx(getToken(currentConfig))
 				client, err := api.GetClient(getServer(currentConfig))
 				if err != nil {
-					c.Err(err)
+					reportError(c, err)
 					return
 				}
 				_, _, err = client.EnvironmentsApi.GetEnvironment(auth, currentProject, p.Keys()[0])
 				if err != nil {
-					c.Printf("No environment \"%s\"\n", p.Keys()[0])
+					reportError(c, fmt.Errorf(`no environment "%s"`, p.Keys()[0]))
 					return
 				}
 				currentEnvironment = c.Args[0]
@@ -219,7 +226,7 @@ func createShell(interactive bool) *ishell.Shell {
 				auth := api.GetAuthCtx(getToken(configKey))
 				client, err := api.GetClient(getServer(configKey))
 				if err != nil {
-					c.Err(err)
+					reportError(c, err)
 					return
 				}
 				if p.Config() != nil {
@@ -229,19 +236,19 @@ func createShell(interactive bool) *ishell.Shell {
 				p := perProjectPath{p}
 				_, _, err = client.ProjectsApi.GetProject(auth, p.Project())
 				if err != nil {
-					c.Printf("No project '%s'\n", p.Project())
+					reportError(c, fmt.Errorf(`no project "%s"`, p.Project()))
 					return
 				}
 				_, _, err = client.EnvironmentsApi.GetEnvironment(auth, p.Project(), p.Key())
 				if err != nil {
-					c.Printf("No environment '%s'\n", p.Key())
+					reportError(c, fmt.Errorf(`no environment "%s"`, p.Key()))
 					return
 				}
 				currentProject = p.Project()
 				currentEnvironment = p.Key()
 				dest += fmt.Sprintf("project %s, environment %s", currentProject, currentEnvironment)
 			default:
-				c.Printf("'%s' is not a valid environment\n", p)
+				reportError(c, fmt.Errorf(`"%s" is not a valid environment`, p))
 				return
 			}
 			c.Printf("Switched to '%s'\n", dest)
@@ -268,12 +275,27 @@ func createShell(interactive bool) *ishell.Shell {
 	addAuditLogCommands(shell)
 	addTokenCommands(shell)
 	addGoalCommands(shell)
+	addRefsCommands(shell)
+	addExportSDKDataCommands(shell)
+	addServeSDKCommands(shell)
+	return shell
+}
+
+// configureShell sets up the shell for the current config, once the command line has been parsed
+func configureShell(shell *ishell.Shell, interactive bool) {
+	shell.SetHomeHistoryPath(".ldc_history")
+
+	prompt := fmt.Sprintf("%s/%s> ", currentProject, currentEnvironment)
+	if currentConfig != nil {
+		prompt = fmt.Sprintf(`[%s] %s`, *currentConfig, prompt)
+	}
+	shell.SetPrompt(prompt)
 
 	isJSON := viper.GetBool("json")
 	shell.Set(cJSON, isJSON)
 	if !isJSON {
 		if configViper.ConfigFileUsed() != "" {
-			fmt.Printf("Using config file: %s\n", configViper.ConfigFileUsed())
+			fmt.Fprintf(os.Stderr, "Using config file: %s\n", configViper.ConfigFileUsed())
 		}
 	}
 
@@ -283,25 +305,47 @@ func createShell(interactive bool) *ishell.Shell {
 	}
 
 	shell.Set(cINTERACTIVE, interactive)
-	return shell
 }
 
-func runRootCmd(cmd *cobra.Command, args []string) {
-	shell := createShell(false)
-	if len(args) == 0 {
-		_ = cmd.Usage()
-		fmt.Print(shell.HelpText())
-		os.Exit(0)
-	}
-	err := shell.Process(args...)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
-		os.Exit(1)
+// shellArgs removes global flags from the command line, leaving any options for the shell command to parse itself
+func shellArgs(args []string) (result []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(result, args[i+1:]...)
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			result = append(result, arg)
+			continue
+		}
+		if !strings.HasPrefix(arg, "--") {
+			// a shorthand, which may be followed by its value
+			flag := pflag.CommandLine.ShorthandLookup(arg[1:2])
+			if flag == nil {
+				result = append(result, arg)
+				continue
+			}
+			if len(arg) == 2 && flag.NoOptDefVal == "" {
+				i++ // skip the value
+			}
+			continue
+		}
+		parts := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)
+		flag := pflag.CommandLine.Lookup(parts[0])
+		if flag == nil {
+			result = append(result, arg)
+			continue
+		}
+		if len(parts) == 1 && flag.NoOptDefVal == "" {
+			i++ // skip the value
+		}
 	}
+	return result
 }
 
 func runShellCmd(cmd *cobra.Command, args []string) {
-	shell := createShell(true)
+	shell := commandShell
+	configureShell(shell, true)
 	shell.Printf("LaunchDarkly CLI %s\n", Version)
 	_ = shell.Process("pwd")
 	shell.Run()
@@ -334,13 +378,13 @@ func setJSONMode(c *ishell.Context) {
 	if len(c.Args) == 1 {
 		value = c.Args[0]
 		if !containsString(boolOptions, strings.ToLower(value)) {
-			c.Println(`Value must be "true" or "false"`)
+			reportError(c, errors.New(`value must be "true" or "false"`))
 			return
 		}
 	} else {
-		choice := c.MultiChoice(boolOptions, "Show JSON? ")
-		if choice < 0 {
-			c.Println("Value unchanged")
+		choice, err := multiChoice(c, boolOptions, "Show JSON? ")
+		if err != nil {
+			reportError(c, err)
 			return
 		}
 		value = boolOptions[choice]
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/spf13/pflag"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	"github.com/launchdarkly/ldc/cmd/internal/relay"
+	"github.com/launchdarkly/ldc/cmd/internal/sdkdata"
+)
+
+const (
+	defaultServeSDKHost         = "127.0.0.1"
+	defaultServeSDKPort         = 8030
+	defaultServeSDKFileInterval = time.Second
+	defaultServeSDKAPIInterval  = 10 * time.Second
+)
+
+func addServeSDKCommands(shell *ishell.Shell) {
+	shell.AddCmd(withFlags(&ishell.Cmd{
+		Name:      "serve-sdk",
+		Help:      "serve sdk streaming and polling endpoints from an environment, fetched every 10s, or an exported file, checked every second: serve-sdk [--from /project/env|file.json] [--host address] [--port port] [--interval duration]",
+		Func:      serveSDK,
+		Completer: environmentCompleter,
+	}, serveSDKFlagSet))
+}
+
+func serveSDKFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("serve-sdk", pflag.ContinueOnError)
+	flags.String("from", "", "environment path or file written by export-sdk-data (defaults to the current environment)")
+	flags.String("host", defaultServeSDKHost,
+		"address to listen on; 0.0.0.0 listens on every interface, exposing targeted user keys to the network")
+	flags.Int("port", defaultServeSDKPort, "port to listen on")
+	flags.Duration("interval", 0, fmt.Sprintf("how often to check for changes (default %s for files, %s for environments, which are fetched in full each time)",
+		defaultServeSDKFileInterval, defaultServeSDKAPIInterval))
+	return flags
+}
+
+// sdkDataSource loads the data to serve and reports whether it may have changed since the last load
+type sdkDataSource struct {
+	description string
+	load        func() (sdkdata.Data, error)
+	modified    func() bool
+}
+
+func fileSDKDataSource(filename string) sdkDataSource {
+	var lastModified time.Time
+	return sdkDataSource{
+		description: filename,
+		load: func() (sdkdata.Data, error) {
+			info, err := os.Stat(filename)
+			if err != nil {
+				return sdkdata.Data{}, err
+			}
+			bytes, err := ioutil.ReadFile(filename)
+			if err != nil {
+				return sdkdata.Data{}, err
+			}
+			data, err := sdkdata.Parse(bytes)
+			if err != nil {
+				return sdkdata.Data{}, fmt.Errorf("invalid data in %s: %s", filename, err)
+			}
+			lastModified = info.ModTime()
+			return data, nil
+		},
+		modified: func() bool {
+			info, err := os.Stat(filename)
+			return err == nil && !info.ModTime().Equal(lastModified)
+		},
+	}
+}
+
+func envSDKDataSource(envPath perProjectPath) sdkDataSource {
+	return sdkDataSource{
+		description: envPath.String(),
+		load: func() (sdkdata.Data, error) {
+			data, err := getSDKData(envPath)
+			if err != nil {
+				return sdkdata.Data{}, err
+			}
+			return *data, nil
+		},
+		// the api has no version for a whole environment, so it is fetched every time and the relay pushes only the
+		// flags and segments that differ
+		modified: func() bool { return true },
+	}
+}
+
+func serveSDK(c *ishell.Context) {
+	flags := serveSDKFlagSet()
+	if !parseFlags(c, flags) {
+		return
+	}
+	from, _ := flags.GetString("from")
+	host, _ := flags.GetString("host")
+	port, _ := flags.GetInt("port")
+	interval, _ := flags.GetDuration("interval")
+	if len(c.Args) > 1 || (len(c.Args) == 1 && from != "") {
+		reportError(c, errTooManyArgs)
+		return
+	}
+	if len(c.Args) == 1 {
+		from = c.Args[0]
+	}
+
+	var source sdkDataSource
+	if info, err := os.Stat(from); from != "" && err == nil && !info.IsDir() {
+		source = fileSDKDataSource(from)
+		if interval == 0 {
+			interval = defaultServeSDKFileInterval
+		}
+	} else {
+		envPath, err := realEnvPath(from)
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		source = envSDKDataSource(envPath)
+		if interval == 0 {
+			interval = defaultServeSDKAPIInterval
+		}
+	}
+
+	data, err := source.load()
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	server := relay.NewServer(data)
+
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	httpServer := &http.Server{Handler: server}
+	served := make(chan error, 1)
+	go func() {
+		served <- httpServer.Serve(listener)
+	}()
+
+	c.Printf("Serving %d flags and %d segments from %s on http://%s\n", len(data.Flags), len(data.Segments), source.description, address)
+	c.Println("Set the sdk base and stream uris to this address.  Press Ctrl-C to stop.")
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !source.modified() {
+				continue
+			}
+			data, err := source.load()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Unable to reload %s: %s\n", source.description, err)
+				continue
+			}
+			changes, err := server.Update(data)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Unable to update sdks: %s\n", err)
+				continue
+			}
+			if changes > 0 {
+				c.Printf("%s Pushed %d changes\n", time.Now().Format("15:04:05"), changes)
+			}
+		case <-interrupt:
+			_ = httpServer.Close()
+			return
+		case err := <-served:
+			reportError(c, err)
+			return
+		}
+	}
+}
@@ -2,18 +2,22 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"reflect"
 	"strings"
 
-	"github.com/mattbaird/jsonpatch"
+	"github.com/mattn/go-isatty"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/pflag"
 	ishell "gopkg.in/abiosoft/ishell.v2"
 
-	ldapi "github.com/launchdarkly/api-client-go"
+	"github.com/launchdarkly/ldc/cmd/internal/merge"
 	"github.com/launchdarkly/ldc/cmd/internal/path"
 )
 
@@ -23,13 +27,25 @@ const (
 	cJSON        = "json"
 )
 
+// output formats for reports
+const (
+	formatTable    = "table"
+	formatCSV      = "csv"
+	formatMarkdown = "markdown"
+	// formatJSON is accepted by reports that are also exported for analysis
+	formatJSON = "json"
+)
+
+var reportFormats = []string{formatTable, formatCSV, formatMarkdown}
+
 var errTooManyArgs = errors.New("too many arguments")
 var errTooFewArgs = errors.New("too few arguments")
 var errNotFound = errors.New("not found")
 var errAborted = errors.New("aborted")
+var errNoTerminal = errors.New("input is required, but stdin is not a terminal")
 
 func confirmDelete(c *ishell.Context, name string, expectedValue string) bool {
-	if !isInteractive(c) {
+	if !isInteractive(c) || !canPrompt() {
 		return true
 	}
 	c.Printf("Re-enter the %s '%s' to delete: ", name, expectedValue)
@@ -82,32 +98,34 @@ func nonFinalCompleter(completer func(args []string) []string) func(args []strin
 	}
 }
 
-func editFile(c *ishell.Context, original []byte) (patch *ldapi.PatchComment, err error) {
+// editDocument opens json in the editor, converted to yaml if the format is yaml, until it is valid, free of conflict
+// markers and passes check, which returns any problems with the edited json.  It returns the edited json, or nil if
+// the edit is aborted.
+func editDocument(c *ishell.Context, original []byte, format string, check func(data []byte) []string) ([]byte, error) {
+	if !canPrompt() {
+		return nil, errNoTerminal
+	}
 	editor := c.Get(cEDITOR).(string)
-	cmd := exec.Command("command", "-v", editor) // nolint:gosec // ok to launch subprocess with variable
-	editorPathRaw, err := cmd.Output()
+	editorPath, err := exec.LookPath(editor)
 	if err != nil {
-		c.Err(err)
 		return nil, err
 	}
-	editorPath := strings.TrimSpace(string(editorPathRaw))
 
-	var patchOps []jsonpatch.JsonPatchOperation
-	current := original
+	current, err := toEditFormat(original, format)
+	if err != nil {
+		return nil, err
+	}
 	for {
-		file, err := ioutil.TempFile("/tmp", "ldc")
+		file, err := ioutil.TempFile("/tmp", "ldc*."+format)
 		if err != nil {
-			c.Err(err)
 			return nil, err
 		}
 		name := file.Name()
 		_, err = file.Write(current)
 		if err != nil {
-			c.Err(err)
 			return nil, err
 		}
 		if err := file.Close(); err != nil {
-			c.Err(err)
 			return nil, err
 		}
 
@@ -116,70 +134,47 @@ func editFile(c *ishell.Context, original []byte) (patch *ldapi.PatchComment, er
 			return nil, err
 		}
 		if _, err := proc.Wait(); err != nil {
-			c.Err(err)
-			return nil, err
-		}
-
-		file, err = os.Open(name) // nolint:gosec // G304: Potential file inclusion via variable // ok because we created name
-		if err != nil {
 			return nil, err
 		}
 
-		newData, fileErr := ioutil.ReadAll(file)
+		newData, fileErr := ioutil.ReadFile(name) // nolint:gosec // G304: Potential file inclusion via variable // ok because we created name
 
-		err = os.Remove(name)
-		if err != nil {
-			c.Println("Unable to delete temporary file: %s", err)
+		if err := os.Remove(name); err != nil {
+			c.Printf("Unable to delete temporary file: %s\n", err)
 		}
 
 		if fileErr != nil {
-			c.Println("Unable to read file: %s", err)
+			c.Printf("Unable to read file: %s\n", fileErr)
 			c.Print("Try again? [y]/n  ")
-			if !yesOrNo(c) {
-				c.Println("Edit aborted")
-				break
+		} else if merge.HasMarkers(newData) {
+			c.Print("Conflict markers remain. Make changes? [y]/n ")
+		} else if data, err := fromEditFormat(newData, format); err != nil {
+			c.Printf("Unable to parse %s: %s\n", format, err)
+			c.Print("Make changes? [y]/n ")
+		} else if problems := runCheck(check, data); len(problems) > 0 {
+			c.Println("The changes are not valid:")
+			for _, problem := range problems {
+				c.Printf("  %s\n", problem)
 			}
+			c.Print("Make changes? [y]/n ")
+		} else {
+			return data, nil
 		}
-		if err := file.Close(); err != nil {
-			return nil, err
+		if !yesOrNo(c) {
+			c.Println("Edit aborted")
+			return nil, nil
 		}
-
-		patchOps, err = jsonpatch.CreatePatch(original, newData)
-		if err != nil {
-			patchOps = nil
-			if err.Error() == "Invalid JSON Document" {
-				c.Print("Unable to parse json. Make changes? [y]/n ")
-			} else {
-				c.Printf("Unable to create patch: %s\n", err.Error())
-				c.Print("Make changes? [y]/n ")
-			}
-			if !yesOrNo(c) {
-				c.Println("Edit aborted")
-				break
-			}
+		if fileErr == nil {
 			current = newData
-			continue
 		}
-
-		break
-	}
-
-	if len(patchOps) == 0 {
-		return nil, nil
 	}
+}
 
-	var patchComment ldapi.PatchComment
-	for _, op := range patchOps {
-		patchComment.Patch = append(patchComment.Patch, ldapi.PatchOperation{
-			Op:    op.Operation,
-			Path:  op.Path,
-			Value: &op.Value,
-		})
+func runCheck(check func(data []byte) []string, data []byte) []string {
+	if check == nil {
+		return nil
 	}
-
-	c.Print("Enter comment: ")
-	patchComment.Comment = c.ReadLine()
-	return &patchComment, nil
+	return check(data)
 }
 
 func firstOrEmpty(args []string) string {
@@ -214,9 +209,36 @@ func isInteractive(c *ishell.Context) bool {
 	return reflect.DeepEqual(c.Get(cINTERACTIVE), true)
 }
 
+// canPrompt returns whether the user can be asked for input, which needs stdin to be a terminal so that scripts never
+// wait for an answer
+func canPrompt() bool {
+	return isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd())
+}
+
+// readLine reads a line of input, failing with errNoTerminal if the user cannot be asked for it
+func readLine(c *ishell.Context) (string, error) {
+	if !canPrompt() {
+		return "", errNoTerminal
+	}
+	return c.ReadLineErr()
+}
+
+// multiChoice asks the user to choose one of the options, failing with errNoTerminal if they cannot be asked and with
+// errAborted if they do not choose
+func multiChoice(c *ishell.Context, options []string, text string) (int, error) {
+	if !canPrompt() {
+		return -1, errNoTerminal
+	}
+	choice := c.MultiChoice(options, text)
+	if choice < 0 {
+		return -1, errAborted
+	}
+	return choice, nil
+}
+
 func renderPagedTable(c *ishell.Context, buf bytes.Buffer) {
 	if buf.Len() > 1000 {
-		c.Err(c.ShowPaged(buf.String()))
+		reportError(c, c.ShowPaged(buf.String()))
 	} else {
 		c.Print(buf.String())
 	}
@@ -225,13 +247,90 @@ func renderPagedTable(c *ishell.Context, buf bytes.Buffer) {
 func printJSON(c *ishell.Context, data interface{}) {
 	bytes, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
-		c.Err(err)
+		reportError(c, err)
 		return
 	}
 
 	c.Print(string(bytes) + "\n")
 }
 
+// parseFlags parses named options from the command arguments, leaving the positional arguments in c.Args
+func parseFlags(c *ishell.Context, flags *pflag.FlagSet) bool {
+	if err := flags.Parse(c.Args); err != nil {
+		reportError(c, err)
+		return false
+	}
+	c.Args = flags.Args()
+	return true
+}
+
+// addTagFlags adds the options that change the tags of a resource
+func addTagFlags(flags *pflag.FlagSet) {
+	flags.StringSlice("tags", nil, "replace the tags with this comma-separated list")
+	flags.StringArray("add-tag", nil, "add a tag")
+	flags.StringArray("remove-tag", nil, "remove a tag")
+}
+
+// applyTagFlags returns the tags as changed by the tag options
+func applyTagFlags(flags *pflag.FlagSet, tags []string) []string {
+	result := append([]string{}, tags...)
+	if flags.Changed("tags") {
+		replacement, _ := flags.GetStringSlice("tags")
+		result = append([]string{}, replacement...)
+	}
+	added, _ := flags.GetStringArray("add-tag")
+	for _, tag := range added {
+		if !containsString(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	removed, _ := flags.GetStringArray("remove-tag")
+	kept := result[:0]
+	for _, tag := range result {
+		if !containsString(removed, tag) {
+			kept = append(kept, tag)
+		}
+	}
+	return kept
+}
+
+// renderReport renders rows as a table, csv or markdown
+func renderReport(c *ishell.Context, format string, header []string, rows [][]string) error {
+	buf := bytes.Buffer{}
+	switch format {
+	case formatCSV:
+		w := csv.NewWriter(&buf)
+		if err := w.Write(header); err != nil {
+			return err
+		}
+		if err := w.WriteAll(rows); err != nil {
+			return err
+		}
+		c.Print(buf.String())
+		return nil
+	case formatMarkdown:
+		table := tablewriter.NewWriter(&buf)
+		table.SetHeader(header)
+		table.SetAutoFormatHeaders(false)
+		table.SetAutoWrapText(false)
+		table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+		table.SetCenterSeparator("|")
+		table.AppendBulk(rows)
+		table.Render()
+		c.Print(buf.String())
+		return nil
+	case formatTable, "":
+		table := tablewriter.NewWriter(&buf)
+		table.SetHeader(header)
+		table.SetAutoWrapText(false)
+		table.AppendBulk(rows)
+		table.Render()
+		renderPagedTable(c, buf)
+		return nil
+	}
+	return fmt.Errorf(`unknown format "%s", expected one of %s`, format, strings.Join(reportFormats, ", "))
+}
+
 func ifNotBlank(s string, defaultValue string) string {
 	if strings.TrimSpace(s) == "" {
 		return defaultValue
@@ -3,7 +3,7 @@ module github.com/launchdarkly/ldc
 require (
 	github.com/BurntSushi/toml v0.3.1 // indirect
 	github.com/abiosoft/ishell v2.0.0+incompatible // indirect
-	github.com/abiosoft/readline v0.0.0-20180607040430-155bce2042db // indirect
+	github.com/abiosoft/readline v0.0.0-20180607040430-155bce2042db
 	github.com/chzyer/logex v1.1.10 // indirect
 	github.com/chzyer/test v0.0.0-20180213035817-a1ea475d72b1 // indirect
 	github.com/fatih/color v1.7.0 // indirect
@@ -12,7 +12,7 @@ require (
 	github.com/launchdarkly/api-client-go v0.0.0-20190111200008-3cb23d7484ad
 	github.com/mattbaird/jsonpatch v0.0.0-20171005235357-81af80346b1a
 	github.com/mattn/go-colorable v0.0.9 // indirect
-	github.com/mattn/go-isatty v0.0.4 // indirect
+	github.com/mattn/go-isatty v0.0.4
 	github.com/mattn/go-runewidth v0.0.4 // indirect
 	github.com/mitchellh/go-homedir v1.0.0
 	github.com/olekukonko/tablewriter v0.0.1
@@ -29,4 +29,5 @@ require (
 	golang.org/x/sys v0.0.0-20190109145017-48ac38b7c8cb // indirect
 	google.golang.org/appengine v1.4.0 // indirect
 	gopkg.in/abiosoft/ishell.v2 v2.0.0
+	gopkg.in/yaml.v2 v2.2.2
 )
@@ -3,12 +3,16 @@ package goalapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"regexp"
+	"time"
 
 	ldapi "github.com/launchdarkly/api-client-go"
 
@@ -23,9 +27,24 @@ const (
 	// PageView indicates a page view goal
 	PageView = "pageview"
 
+	// URLMatcherKindCanonical matches a url ignoring its query string and fragment
+	URLMatcherKindCanonical = "canonical"
+	// URLMatcherKindExact matches a url exactly
+	URLMatcherKindExact = "exact"
+	// URLMatcherKindRegex matches urls with a regular expression
+	URLMatcherKindRegex = "regex"
+	// URLMatcherKindSubstring matches urls containing a string
+	URLMatcherKindSubstring = "substring"
+
+	// DefaultTimeout limits each request of a new client
+	DefaultTimeout = 30 * time.Second
+
 	defaultServerURL = "https://app.launchdarkly.com"
 )
 
+// ErrConflict matches errors from patches that fail because the goal has been changed since it was fetched
+var ErrConflict = api.ErrConflict
+
 // Kinds are all the kinds that we can use for a goal
 var Kinds = []string{Click, Custom, PageView}
 
@@ -66,6 +85,53 @@ type GoalURLMatchers struct {
 	SubstringURLs []URLMatcherSubstring `json:"substringUrls,omitempty"`
 }
 
+// NewURLMatchers creates the url matchers for a click or page view goal, checking that each regex compiles
+func NewURLMatchers(exact, canonical, substrings, patterns []string) (GoalURLMatchers, error) {
+	var matchers GoalURLMatchers
+	for _, u := range exact {
+		matchers.ExactURLs = append(matchers.ExactURLs, URLMatcherExact{URLMatcherBase{URLMatcherKindExact}, u})
+	}
+	for _, u := range canonical {
+		matchers.CanonicalURLs = append(matchers.CanonicalURLs, URLMatcherCanonical{URLMatcherBase{URLMatcherKindCanonical}, u})
+	}
+	for _, s := range substrings {
+		matchers.SubstringURLs = append(matchers.SubstringURLs, URLMatcherSubstring{URLMatcherBase{URLMatcherKindSubstring}, s})
+	}
+	for _, p := range patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return GoalURLMatchers{}, fmt.Errorf("invalid url regex %q: %s", p, err)
+		}
+		matchers.RegexURLs = append(matchers.RegexURLs, URLMatcherRegex{URLMatcherBase{URLMatcherKindRegex}, p})
+	}
+	if matchers.Empty() {
+		return GoalURLMatchers{}, errors.New("at least one url matcher is required")
+	}
+	return matchers, nil
+}
+
+// Empty returns true if there are no matchers
+func (m GoalURLMatchers) Empty() bool {
+	return len(m.ExactURLs)+len(m.CanonicalURLs)+len(m.RegexURLs)+len(m.SubstringURLs) == 0
+}
+
+// Describe returns each matcher as its kind and what it matches, e.g. "exact: https://example.com/"
+func (m GoalURLMatchers) Describe() []string {
+	var descriptions []string
+	for _, u := range m.ExactURLs {
+		descriptions = append(descriptions, URLMatcherKindExact+": "+u.URL)
+	}
+	for _, u := range m.CanonicalURLs {
+		descriptions = append(descriptions, URLMatcherKindCanonical+": "+u.URL)
+	}
+	for _, u := range m.SubstringURLs {
+		descriptions = append(descriptions, URLMatcherKindSubstring+": "+u.Substring)
+	}
+	for _, u := range m.RegexURLs {
+		descriptions = append(descriptions, URLMatcherKindRegex+": "+u.Pattern)
+	}
+	return descriptions
+}
+
 // Goal describes the goal type.
 type Goal struct {
 	// ID of the goal
@@ -83,6 +149,9 @@ type Goal struct {
 	// Key for custom goals
 	Key *string `json:"key,omitempty"`
 
+	// Selector is the css selector of the elements that click goals count clicks on
+	Selector string `json:"selector,omitempty"`
+
 	// IsActive indicates whether the goal is being tracked by a flag
 	IsActive bool `json:"isActive,omitempty"`
 
@@ -122,6 +191,8 @@ type ExperimentResults struct {
 	ZScore          float64   `json:"z_score"`
 	Control         Variation `json:"control"`
 	Experiment      Variation `json:"experiment"`
+	// Variations holds the results for each variation of a multivariate flag, in the order of the flag's variations
+	Variations []Variation `json:"variations,omitempty"`
 }
 
 // Variation holds data about each result
@@ -133,177 +204,159 @@ type Variation struct {
 	ConfidenceInterval float64 `json:"confidenceInterval"`
 }
 
-// Context is used to access an environment
-type Context struct {
-	host  string
-	token string
-}
+// Client accesses the goals of an environment
+type Client struct {
+	host       string
+	token      string
+	httpClient *http.Client
 
-// NewContext creates a context for accessing an environment
-func NewContext(host, token string) Context {
-	return Context{
-		host:  host,
-		token: token,
-	}
+	// Timeout limits each request, unless its context ends sooner.  Zero means no limit.
+	Timeout time.Duration
 }
 
-func newRequest(ctx Context, method, path string, body io.Reader) (*http.Request, error) {
-	host := ctx.host
-	if host == "" {
-		host = defaultServerURL
+// NewClient creates a client for the environment with the given api key
+func NewClient(host, token string) *Client {
+	httpClient := api.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
 	}
-	u, _ := url.Parse(host)
-	u.Path = path
-	u.RawPath = ""
-	req, err := http.NewRequest(method, u.String(), body)
-	if err != nil {
-		return nil, err
+	return &Client{
+		host:       host,
+		token:      token,
+		httpClient: httpClient,
+		Timeout:    DefaultTimeout,
 	}
-	req.Header.Add("Authorization", ctx.token)
-	req.Header.Add("Content-Type", "application/json")
-	return req, nil
 }
 
-// GetGoal returns the goal with a given id
-func GetGoal(ctx Context, id string) (*Goal, error) {
-	req, _ := newRequest(ctx, http.MethodGet, fmt.Sprintf("/api/goals/%s", id), nil)
+// APIError is returned when the api responds with an error
+type APIError = api.Error
 
-	resp, err := api.HTTPClient.Do(req)
-	if err != nil {
-		return nil, err
+// do sends a request, decoding the response into result unless it is nil.  Any status other than expected is
+// returned as an *APIError.
+func (c *Client) do(ctx context.Context, method, path string, body interface{}, expected int, result interface{}) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewBuffer(data)
 	}
-	_ = resp.Body.Close()
 
-	var goal Goal
-	if err := json.Unmarshal(body, &goal); err != nil {
-		return nil, err
+	host := c.host
+	if host == "" {
+		host = defaultServerURL
 	}
-	return &goal, nil
-}
-
-// GetExperimentResults returns the experiment results for a specific flag and goal
-func GetExperimentResults(ctx Context, goalID string, flagKey string) (*ExperimentResults, error) {
-	req, _ := newRequest(ctx, http.MethodGet, fmt.Sprintf("/api/features/%s/goals/%s/results", flagKey, goalID), nil)
-
-	resp, err := api.HTTPClient.Do(req)
+	u, err := url.Parse(host)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("unable to parse server: %s", err)
 	}
-
-	body, err := ioutil.ReadAll(resp.Body)
+	ref, err := url.Parse(path)
 	if err != nil {
-		return nil, err
-	}
-	_ = resp.Body.Close()
-
-	var experimentResults ExperimentResults
-	if err := json.Unmarshal(body, &experimentResults); err != nil {
-		return nil, err
+		return err
 	}
-	return &experimentResults, nil
-}
-
-// GetGoals returns all goals for the current environment
-func GetGoals(ctx Context) ([]Goal, error) {
-	req, _ := newRequest(ctx, http.MethodGet, fmt.Sprintf("/api/goals"), nil)
-
-	resp, err := api.HTTPClient.Do(req)
+	u = u.ResolveReference(ref)
+	req, err := http.NewRequest(method, u.String(), reqBody)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	req = req.WithContext(ctx)
+	req.Header.Add("Authorization", c.token)
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Set("User-Agent", api.UserAgent)
 
-	body, err := ioutil.ReadAll(resp.Body)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	respBody, err := ioutil.ReadAll(resp.Body)
 	_ = resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected response: %s", resp.Status)
+	if err != nil {
+		return err
 	}
 
-	var respData struct {
-		Items []Goal
+	if resp.StatusCode != expected {
+		return api.NewError(resp.StatusCode, respBody)
 	}
-
-	if err := json.Unmarshal(body, &respData); err != nil {
-		return nil, fmt.Errorf("unable to unmarshal: %s: %s", body, err)
+	if result == nil {
+		return nil
 	}
-
-	return respData.Items, nil
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return fmt.Errorf("unable to unmarshal: %s: %s", respBody, err)
+	}
+	return nil
 }
 
-// CreateGoal creates a goal in the current environment
-func CreateGoal(ctx Context, goal Goal) (*Goal, error) {
-	body, _ := json.Marshal(goal)
-	req, _ := newRequest(ctx, http.MethodPost, fmt.Sprintf("/api/goals"), bytes.NewBuffer(body))
-
-	resp, err := api.HTTPClient.Do(req)
-	if err != nil {
+// GetGoal returns the goal with a given id
+func (c *Client) GetGoal(ctx context.Context, id string) (*Goal, error) {
+	var goal Goal
+	if err := c.do(ctx, http.MethodGet, "/api/goals/"+url.PathEscape(id), nil, http.StatusOK, &goal); err != nil {
 		return nil, err
 	}
+	return &goal, nil
+}
 
-	respBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
+// GetExperimentResults returns the experiment results for a specific flag and goal
+func (c *Client) GetExperimentResults(ctx context.Context, goalID string, flagKey string) (*ExperimentResults, error) {
+	var results ExperimentResults
+	resultsPath := fmt.Sprintf("/api/features/%s/goals/%s/results", url.PathEscape(flagKey), url.PathEscape(goalID))
+	if err := c.do(ctx, http.MethodGet, resultsPath, nil, http.StatusOK, &results); err != nil {
 		return nil, err
 	}
-	_ = resp.Body.Close()
+	return &results, nil
+}
 
-	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("unexpected response (%s): %s", resp.Status, respBody)
+// GetGoals returns all goals for the environment, following the links to each page of them
+func (c *Client) GetGoals(ctx context.Context) ([]Goal, error) {
+	var goals []Goal
+	seen := make(map[string]bool)
+	for next := "/api/goals"; next != "" && !seen[next]; {
+		seen[next] = true
+		var page struct {
+			Items []Goal `json:"items"`
+			Links struct {
+				Next *struct {
+					Href string `json:"href"`
+				} `json:"next"`
+			} `json:"_links"`
+		}
+		if err := c.do(ctx, http.MethodGet, next, nil, http.StatusOK, &page); err != nil {
+			return nil, err
+		}
+		goals = append(goals, page.Items...)
+		next = ""
+		if page.Links.Next != nil && len(page.Items) > 0 {
+			next = page.Links.Next.Href
+		}
 	}
+	return goals, nil
+}
 
+// CreateGoal creates a goal in the environment
+func (c *Client) CreateGoal(ctx context.Context, goal Goal) (*Goal, error) {
 	var newGoal Goal
-	if err := json.Unmarshal(respBody, &goal); err != nil {
+	if err := c.do(ctx, http.MethodPost, "/api/goals", goal, http.StatusCreated, &newGoal); err != nil {
 		return nil, err
 	}
 	return &newGoal, nil
 }
 
-// DeleteGoal deletes a goal in the current environment
-func DeleteGoal(ctx Context, id string) error {
-	req, _ := newRequest(ctx, http.MethodDelete, fmt.Sprintf("/api/goals/%s", id), nil)
-
-	resp, err := api.HTTPClient.Do(req)
-	if err != nil {
-		return err
-	}
-
-	if resp.StatusCode != http.StatusNoContent {
-		respBody, _ := ioutil.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected response (%s): %s", resp.Status, respBody)
-	}
-
-	return nil
+// DeleteGoal deletes a goal in the environment
+func (c *Client) DeleteGoal(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/goals/"+url.PathEscape(id), nil, http.StatusNoContent, nil)
 }
 
-// PatchGoal patches a goal in the current environment
-func PatchGoal(ctx Context, id string, patchComment ldapi.PatchComment) (*Goal, error) {
-	body, _ := json.Marshal(patchComment)
-	req, _ := newRequest(ctx, http.MethodPatch, fmt.Sprintf("/api/goals/%s", id), bytes.NewBuffer(body))
-
-	resp, err := api.HTTPClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	respBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	_ = resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected response: %s", resp.Status)
-	}
-
+// PatchGoal patches a goal in the environment.  The error matches ErrConflict if the goal has been changed since it
+// was fetched.
+func (c *Client) PatchGoal(ctx context.Context, id string, patchComment ldapi.PatchComment) (*Goal, error) {
 	var newGoal Goal
-	if err := json.Unmarshal(respBody, &newGoal); err != nil {
+	if err := c.do(ctx, http.MethodPatch, "/api/goals/"+url.PathEscape(id), patchComment, http.StatusOK, &newGoal); err != nil {
 		return nil, err
 	}
 	return &newGoal, nil
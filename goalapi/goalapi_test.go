@@ -0,0 +1,88 @@
+package goalapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewURLMatchers(t *testing.T) {
+	matchers, err := NewURLMatchers([]string{"https://example.com/"}, []string{"https://example.com/cart"}, []string{"checkout"}, []string{`^https://example\.com/items/\d+$`})
+	require.NoError(t, err)
+	assert.Equal(t, URLMatcherKindExact, matchers.ExactURLs[0].Kind)
+	assert.Equal(t, []string{
+		"exact: https://example.com/",
+		"canonical: https://example.com/cart",
+		"substring: checkout",
+		`regex: ^https://example\.com/items/\d+$`,
+	}, matchers.Describe())
+
+	_, err = NewURLMatchers(nil, nil, nil, []string{"items/("})
+	assert.Error(t, err, "invalid regex")
+
+	_, err = NewURLMatchers(nil, nil, nil, nil)
+	assert.Error(t, err, "no matchers")
+}
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "api-key", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/goals" && r.URL.Query().Get("offset") == "":
+			_, _ = w.Write([]byte(`{"items":[{"_id":"g1"}],"_links":{"next":{"href":"/api/goals?offset=1"}}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/goals":
+			_, _ = w.Write([]byte(`{"items":[{"_id":"g2"}],"_links":{}}`))
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"_id":"g3","name":"new"}`))
+		case r.Method == http.MethodPatch:
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"code":"conflict","message":"the goal was changed"}`))
+		case r.URL.Path == "/api/goals/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("not here"))
+		}
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "api-key")
+	ctx := context.Background()
+
+	goals, err := client.GetGoals(ctx)
+	require.NoError(t, err)
+	require.Len(t, goals, 2, "both pages")
+	assert.Equal(t, "g2", goals[1].ID)
+
+	goal, err := client.CreateGoal(ctx, Goal{Name: "new"})
+	require.NoError(t, err)
+	assert.Equal(t, "g3", goal.ID)
+
+	_, err = client.PatchGoal(ctx, "g1", ldapi.PatchComment{})
+	assert.True(t, errors.Is(err, ErrConflict))
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, APIError{StatusCode: http.StatusConflict, Code: "conflict", Message: "the goal was changed"}, *apiErr)
+
+	_, err = client.GetGoal(ctx, "missing")
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "not here (404)", apiErr.Error())
+	assert.False(t, errors.Is(err, ErrConflict))
+
+	client.Timeout = 50 * time.Millisecond
+	_, err = client.GetGoal(ctx, "slow")
+	assert.Error(t, err, "timed out")
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	client.Timeout = 0
+	_, err = client.GetGoals(cancelled)
+	assert.True(t, errors.Is(err, context.Canceled))
+}